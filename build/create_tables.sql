@@ -1,4 +1,32 @@
-CREATE TYPE ad_status_values AS ENUM ('A', 'R', 'C');
+-- The script may be run again on an existing database: every statement below creates only what is missing,
+-- and the migration section brings the tables created by the earlier versions up to date.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ad_status_values') THEN
+        CREATE TYPE ad_status_values AS ENUM ('A', 'R', 'C', 'H', 'B', 'P');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'thread_status_values') THEN
+        CREATE TYPE thread_status_values AS ENUM ('O', 'C');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'application_status_values') THEN
+        CREATE TYPE application_status_values AS ENUM ('P', 'A', 'R');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'user_role_values') THEN
+        CREATE TYPE user_role_values AS ENUM ('user', 'moderator', 'admin');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'report_status_values') THEN
+        CREATE TYPE report_status_values AS ENUM ('O', 'R');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'notification_kind_values') THEN
+        CREATE TYPE notification_kind_values AS ENUM ('S', 'A');
+    END IF;
+END;
+$$;
+
+-- Hidden, blocked and pending ads came after the first version of the ad statuses.
+ALTER TYPE ad_status_values ADD VALUE IF NOT EXISTS 'H';
+ALTER TYPE ad_status_values ADD VALUE IF NOT EXISTS 'B';
+ALTER TYPE ad_status_values ADD VALUE IF NOT EXISTS 'P';
 
 CREATE TABLE IF NOT EXISTS Region (
     id UUID PRIMARY KEY,
@@ -29,6 +57,8 @@ CREATE TABLE IF NOT EXISTS MyUser (
     username TEXT UNIQUE NOT NULL CONSTRAINT user_username_length CHECK (char_length(username) <= 20),
     password_hash TEXT NOT NULL CONSTRAINT user_password_hash_length CHECK (char_length(password_hash) <= 256),
     locality_id UUID REFERENCES Locality (id),
+    -- The first admin is promoted by hand: UPDATE MyUser SET role = 'admin' WHERE username = '...';
+    role user_role_values NOT NULL DEFAULT 'user',
     created_at TIMESTAMP WITH TIME ZONE NOT NULL
 );
 
@@ -43,24 +73,89 @@ CREATE TABLE IF NOT EXISTS Ad (
     animal_id UUID NOT NULL REFERENCES Animal (id),
     breed_id UUID NOT NULL REFERENCES Breed (id),
     contacts TEXT NOT NULL CONSTRAINT ad_contacts_length CHECK (char_length(contacts) <= 128),
+    locality_id UUID REFERENCES Locality (id),
+    latitude FLOAT,
+    longitude FLOAT,
+    anonymous_views INTEGER NOT NULL DEFAULT 0,
+    moderation_reason TEXT CONSTRAINT ad_moderation_reason_length CHECK (char_length(moderation_reason) <= 1024),
+    -- published_at is the last time the ad became Actual, saved searches notify about the ads published since their check.
+    published_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS AdPhoto (
+    id UUID PRIMARY KEY,
+    ad_id UUID NOT NULL REFERENCES Ad (id) ON DELETE CASCADE,
+    url TEXT NOT NULL CONSTRAINT adphoto_url_length CHECK (char_length(url) <= 128),
+    medium_url TEXT NOT NULL CONSTRAINT adphoto_medium_url_length CHECK (char_length(medium_url) <= 128),
+    thumbnail_url TEXT NOT NULL CONSTRAINT adphoto_thumbnail_url_length CHECK (char_length(thumbnail_url) <= 128),
+    position INTEGER NOT NULL,
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- Deferrable so reordering and replacing photos may swap positions within one statement.
+    CONSTRAINT adphoto_position_unique UNIQUE (ad_id, position) DEFERRABLE INITIALLY IMMEDIATE
+);
+
 CREATE TABLE IF NOT EXISTS Favorite (
     user_id UUID REFERENCES MyUser (id),
-    ad_id UUID REFERENCES Ad (id),
+    ad_id UUID REFERENCES Ad (id) ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     PRIMARY KEY (user_id, ad_id)
 );
 
 CREATE TABLE IF NOT EXISTS Watch (
     user_id UUID REFERENCES MyUser (id),
-    ad_id UUID REFERENCES Ad (id),
+    ad_id UUID REFERENCES Ad (id) ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     PRIMARY KEY (user_id, ad_id)
 );
 
+CREATE TABLE IF NOT EXISTS Thread (
+    id UUID PRIMARY KEY,
+    ad_id UUID NOT NULL REFERENCES Ad (id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES MyUser (id),
+    adopter_id UUID NOT NULL REFERENCES MyUser (id),
+    status thread_status_values NOT NULL,
+    owner_read_at TIMESTAMP WITH TIME ZONE,
+    adopter_read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (ad_id, adopter_id)
+);
+
+CREATE TABLE IF NOT EXISTS Message (
+    id UUID PRIMARY KEY,
+    thread_id UUID NOT NULL REFERENCES Thread (id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES MyUser (id),
+    text TEXT NOT NULL CONSTRAINT message_text_length CHECK (char_length(text) <= 4096),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS Application (
+    id UUID PRIMARY KEY,
+    ad_id UUID NOT NULL REFERENCES Ad (id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES MyUser (id),
+    adopter_id UUID NOT NULL REFERENCES MyUser (id),
+    status application_status_values NOT NULL,
+    answers JSONB NOT NULL DEFAULT '{}',
+    message TEXT NOT NULL CONSTRAINT application_message_length CHECK (char_length(message) <= 4096),
+    reply TEXT NOT NULL CONSTRAINT application_reply_length CHECK (char_length(reply) <= 4096),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS AdReport (
+    id UUID PRIMARY KEY,
+    ad_id UUID NOT NULL REFERENCES Ad (id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES MyUser (id),
+    reason TEXT NOT NULL CONSTRAINT ad_report_reason_length CHECK (char_length(reason) <= 32),
+    comment TEXT NOT NULL CONSTRAINT ad_report_comment_length CHECK (char_length(comment) <= 1024),
+    status report_status_values NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
 CREATE TABLE IF NOT EXISTS History (
     user_id UUID PRIMARY KEY REFERENCES MyUser (id),
     animal_id UUID REFERENCES Animal (id),
@@ -71,6 +166,32 @@ CREATE TABLE IF NOT EXISTS History (
     created_at TIMESTAMP WITH TIME ZONE NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS SavedSearch (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES MyUser (id),
+    name TEXT NOT NULL CONSTRAINT saved_search_name_length CHECK (char_length(name) <= 64),
+    params JSONB NOT NULL,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- A notification is either a new match of a saved search ('S') or an application rejected
+-- because the pet was adopted by another applicant ('A').
+CREATE TABLE IF NOT EXISTS SearchNotification (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES MyUser (id),
+    kind notification_kind_values NOT NULL DEFAULT 'S',
+    saved_search_id UUID REFERENCES SavedSearch (id) ON DELETE CASCADE,
+    application_id UUID REFERENCES Application (id) ON DELETE CASCADE,
+    ad_id UUID NOT NULL REFERENCES Ad (id) ON DELETE CASCADE,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (saved_search_id, ad_id),
+    CONSTRAINT search_notification_source CHECK (
+        (kind = 'S') = (saved_search_id IS NOT NULL) AND (kind = 'A') = (application_id IS NOT NULL)
+    )
+);
+
 CREATE TABLE IF NOT EXISTS GptDescription (
     id UUID PRIMARY KEY,
     color TEXT CONSTRAINT color_length CHECK (char_length(color) <= 32),
@@ -78,31 +199,128 @@ CREATE TABLE IF NOT EXISTS GptDescription (
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL
 );
 
+-- Entries reference no other table, so they outlive the deleted entities and users they describe.
+CREATE TABLE IF NOT EXISTS AuditLog (
+    id UUID PRIMARY KEY,
+    actor_id UUID,
+    actor_username TEXT NOT NULL,
+    action TEXT NOT NULL CONSTRAINT audit_log_action_length CHECK (char_length(action) <= 32),
+    entity_type TEXT NOT NULL CONSTRAINT audit_log_entity_type_length CHECK (char_length(entity_type) <= 32),
+    entity_id UUID NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Migration of the tables created by the earlier versions, CREATE TABLE IF NOT EXISTS leaves them as they were.
+ALTER TABLE MyUser ADD COLUMN IF NOT EXISTS role user_role_values NOT NULL DEFAULT 'user';
+
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS locality_id UUID REFERENCES Locality (id);
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS latitude FLOAT;
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS longitude FLOAT;
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS anonymous_views INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS moderation_reason TEXT CONSTRAINT ad_moderation_reason_length CHECK (char_length(moderation_reason) <= 1024);
+ALTER TABLE Ad ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+-- Ads published before published_at are taken as published when created.
+UPDATE Ad SET published_at = created_at WHERE published_at IS NULL AND status = 'A';
+
+-- Photos uploaded before the variants show the original in their place.
+ALTER TABLE AdPhoto ADD COLUMN IF NOT EXISTS medium_url TEXT CONSTRAINT adphoto_medium_url_length CHECK (char_length(medium_url) <= 128);
+ALTER TABLE AdPhoto ADD COLUMN IF NOT EXISTS thumbnail_url TEXT CONSTRAINT adphoto_thumbnail_url_length CHECK (char_length(thumbnail_url) <= 128);
+UPDATE AdPhoto SET medium_url = url WHERE medium_url IS NULL;
+UPDATE AdPhoto SET thumbnail_url = url WHERE thumbnail_url IS NULL;
+ALTER TABLE AdPhoto ALTER COLUMN medium_url SET NOT NULL;
+ALTER TABLE AdPhoto ALTER COLUMN thumbnail_url SET NOT NULL;
+
+-- Ads created before galleries keep their single photo as the cover, the file is named after the ad.
+INSERT INTO AdPhoto (id, ad_id, url, medium_url, thumbnail_url, position, is_cover, created_at)
+SELECT id, id, photo_url, photo_url, photo_url, 0, TRUE, created_at FROM Ad
+WHERE photo_url IS NOT NULL AND NOT EXISTS (SELECT 1 FROM AdPhoto WHERE AdPhoto.ad_id = Ad.id)
+ON CONFLICT (id) DO NOTHING;
+
+-- Galleries created before the position constraint are renumbered once, then get the constraint too.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'adphoto_position_unique') THEN
+        UPDATE AdPhoto SET position = renumbered.position - 1
+        FROM (SELECT id, row_number() OVER (PARTITION BY ad_id ORDER BY position, created_at, id) AS position FROM AdPhoto) AS renumbered
+        WHERE AdPhoto.id = renumbered.id;
+        ALTER TABLE AdPhoto ADD CONSTRAINT adphoto_position_unique UNIQUE (ad_id, position) DEFERRABLE INITIALLY IMMEDIATE;
+    END IF;
+END;
+$$;
+
+-- Favorites and watches of the first version kept deleted ads from being removed.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'favorite_ad_id_fkey' AND confdeltype <> 'c') THEN
+        ALTER TABLE Favorite DROP CONSTRAINT favorite_ad_id_fkey;
+        ALTER TABLE Favorite ADD CONSTRAINT favorite_ad_id_fkey FOREIGN KEY (ad_id) REFERENCES Ad (id) ON DELETE CASCADE;
+    END IF;
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'watch_ad_id_fkey' AND confdeltype <> 'c') THEN
+        ALTER TABLE Watch DROP CONSTRAINT watch_ad_id_fkey;
+        ALTER TABLE Watch ADD CONSTRAINT watch_ad_id_fkey FOREIGN KEY (ad_id) REFERENCES Ad (id) ON DELETE CASCADE;
+    END IF;
+END;
+$$;
+
+-- Notifications created before the application ones all come from saved searches.
+ALTER TABLE SearchNotification ADD COLUMN IF NOT EXISTS kind notification_kind_values NOT NULL DEFAULT 'S';
+ALTER TABLE SearchNotification ADD COLUMN IF NOT EXISTS application_id UUID REFERENCES Application (id) ON DELETE CASCADE;
+ALTER TABLE SearchNotification ALTER COLUMN saved_search_id DROP NOT NULL;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'search_notification_source') THEN
+        ALTER TABLE SearchNotification ADD CONSTRAINT search_notification_source CHECK (
+            (kind = 'S') = (saved_search_id IS NOT NULL) AND (kind = 'A') = (application_id IS NOT NULL)
+        );
+    END IF;
+END;
+$$;
+
+-- Distances are computed inline by the search queries, the per-row plpgsql function is no longer used.
+DROP FUNCTION IF EXISTS haversine_distance(FLOAT, FLOAT, FLOAT, FLOAT);
+DROP INDEX IF EXISTS ad_photo_ad_id_idx;
+
 CREATE INDEX IF NOT EXISTS locality_region_id_idx ON Locality (region_id);
 CREATE INDEX IF NOT EXISTS breed_animal_id_idx ON Breed (animal_id);
 CREATE INDEX IF NOT EXISTS ad_status_idx ON Ad (status);
 CREATE INDEX IF NOT EXISTS ad_animal_id_idx ON Ad (animal_id);
 CREATE INDEX IF NOT EXISTS ad_breed_id_idx ON Ad (breed_id);
+CREATE INDEX IF NOT EXISTS ad_locality_id_idx ON Ad (locality_id);
+CREATE INDEX IF NOT EXISTS ad_coordinates_idx ON Ad (latitude, longitude);
+CREATE INDEX IF NOT EXISTS locality_coordinates_idx ON Locality (latitude, longitude);
+CREATE INDEX IF NOT EXISTS ad_owner_id_idx ON Ad (owner_id);
+CREATE INDEX IF NOT EXISTS my_user_locality_id_idx ON MyUser (locality_id);
+CREATE INDEX IF NOT EXISTS favorite_ad_id_idx ON Favorite (ad_id);
+CREATE INDEX IF NOT EXISTS watch_ad_id_idx ON Watch (ad_id);
+CREATE INDEX IF NOT EXISTS watch_user_id_created_at_idx ON Watch (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS thread_owner_id_updated_at_idx ON Thread (owner_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS thread_adopter_id_updated_at_idx ON Thread (adopter_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS message_thread_id_created_at_idx ON Message (thread_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS application_pending_idx ON Application (ad_id, adopter_id) WHERE status = 'P';
+CREATE INDEX IF NOT EXISTS application_owner_id_updated_at_idx ON Application (owner_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS application_adopter_id_updated_at_idx ON Application (adopter_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS ad_created_at_idx ON Ad (created_at);
+CREATE INDEX IF NOT EXISTS ad_published_at_idx ON Ad (published_at);
+CREATE UNIQUE INDEX IF NOT EXISTS ad_report_open_idx ON AdReport (ad_id, reporter_id) WHERE status = 'O';
+CREATE INDEX IF NOT EXISTS ad_report_status_created_at_idx ON AdReport (status, created_at);
+CREATE INDEX IF NOT EXISTS ad_text_search_idx ON Ad USING GIN (to_tsvector('russian', COALESCE(title, '') || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS saved_search_user_id_idx ON SavedSearch (user_id);
+CREATE INDEX IF NOT EXISTS search_notification_user_id_created_at_idx ON SearchNotification (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON AuditLog (created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_username_created_at_idx ON AuditLog (actor_username, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_entity_created_at_idx ON AuditLog (entity_type, entity_id, created_at DESC);
 
-CREATE OR REPLACE FUNCTION haversine_distance(
-    lat1 FLOAT, lon1 FLOAT,
-    lat2 FLOAT, lon2 FLOAT
-) RETURNS FLOAT AS $$
-DECLARE
-    r CONSTANT FLOAT := 6371; -- Радиус Земли в километрах
-    dlat FLOAT := radians(lat2 - lat1);
-    dlon FLOAT := radians(lon2 - lon1);
-    a FLOAT;
-    c FLOAT;
-    dist FLOAT;
+-- The audit log is append-only, rows can not be changed or removed even by hand.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
 BEGIN
-    a := sin(dlat / 2)^2 + cos(radians(lat1)) * cos(radians(lat2)) * sin(dlon / 2)^2;
-    c := 2 * atan2(sqrt(a), sqrt(1 - a));
-    dist := r * c;
-
-    -- Логирование входных и выходного значения
-    RAISE NOTICE 'Haversine input: (%, %), (%, %) => dist: % km', lat1, lon1, lat2, lon2, dist;
-
-    RETURN dist;
+    RAISE EXCEPTION 'AuditLog is append-only';
 END;
-$$ LANGUAGE plpgsql IMMUTABLE;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON AuditLog
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 	breedHandlers "pet_adopter/src/breed/handlers"
 	localityHandlers "pet_adopter/src/locality/handlers"
 	regionHandlers "pet_adopter/src/region/handlers"
+	userHandlers "pet_adopter/src/user/handlers"
 )
 
 const (
@@ -27,10 +29,15 @@ const (
 	addBreedURL    = "/api/v1/breeds/add"
 	addRegionURL   = "/api/v1/regions/add"
 	addLocalityURL = "/api/v1/localities/add"
+	loginURL       = "/api/v1/user/login"
+
+	sessionCookieName = "pet_adopter_session"
 )
 
 var (
-	adminToken = ""
+	adminUsername = ""
+	adminPassword = ""
+	accessToken   = ""
 
 	client = http.DefaultClient
 
@@ -41,7 +48,8 @@ func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("failed to load .env file: %v", err)
 	}
-	adminToken = os.Getenv("ADMIN_TOKEN")
+	adminUsername = os.Getenv("ADMIN_USERNAME")
+	adminPassword = os.Getenv("ADMIN_PASSWORD")
 }
 
 type Locality struct {
@@ -50,13 +58,34 @@ type Locality struct {
 	Name      string
 }
 
-func adminRequest(path string, body io.Reader) ([]byte, error) {
-	url := fmt.Sprintf("%s%s?token=%s", host, path, adminToken)
+// login opens a session of a user with the admin role, the catalog can not be changed by anyone else.
+func login() error {
+	reqBody, err := json.Marshal(userHandlers.LoginRequest{Username: adminUsername, Password: adminPassword})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal login request")
+	}
+
+	resp, err := client.Post(host+loginURL, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return errors.Wrap(err, "failed to send login request")
+	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequest(http.MethodPost, url, body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status=%d", resp.StatusCode)
+	}
+
+	accessToken = strings.TrimPrefix(resp.Header.Get("Authorization"), "Bearer ")
+	return nil
+}
+
+func adminRequest(path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, host+path, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create request")
 	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: accessToken})
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -148,6 +177,10 @@ func main() {
 	flag.StringVar(&host, "host", "http://127.0.0.1:8080", "API host in format \"schema://host:port\"")
 	flag.Parse()
 
+	if err := login(); err != nil {
+		log.Fatalf("failed to log in as %s: %v", adminUsername, err)
+	}
+
 	//for animal, breedSlice := range breeds {
 	//	animalID, err := addAnimal(animal)
 	//	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"pet_adopter/src/chatgpt/logic"
 	"pet_adopter/src/chatgpt/request"
 
+	"pet_adopter/src/ad"
 	"pet_adopter/src/config"
 	"pet_adopter/src/middleware"
 
@@ -30,28 +31,59 @@ import (
 	handlersOfAd "pet_adopter/src/ad/handlers"
 	logicOfAd "pet_adopter/src/ad/logic"
 	repoOfAd "pet_adopter/src/ad/repo"
+	storageOfAd "pet_adopter/src/ad/storage"
+
+	handlersOfApplication "pet_adopter/src/application/handlers"
+	logicOfApplication "pet_adopter/src/application/logic"
+	repoOfApplication "pet_adopter/src/application/repo"
 
 	handlersOfAnimal "pet_adopter/src/animal/handlers"
 	logicOfAnimal "pet_adopter/src/animal/logic"
 	repoOfAnimal "pet_adopter/src/animal/repo"
 
+	handlersOfAudit "pet_adopter/src/audit/handlers"
+	logicOfAudit "pet_adopter/src/audit/logic"
+	repoOfAudit "pet_adopter/src/audit/repo"
+
 	handlersOfBreed "pet_adopter/src/breed/handlers"
 	logicOfBreed "pet_adopter/src/breed/logic"
 	repoOfBreed "pet_adopter/src/breed/repo"
 
 	chatGPTRepo "pet_adopter/src/chatgpt/repo"
 
+	handlersOfConversation "pet_adopter/src/conversation/handlers"
+	logicOfConversation "pet_adopter/src/conversation/logic"
+	repoOfConversation "pet_adopter/src/conversation/repo"
+
+	handlersOfFavorite "pet_adopter/src/favorite/handlers"
+	logicOfFavorite "pet_adopter/src/favorite/logic"
+	repoOfFavorite "pet_adopter/src/favorite/repo"
+
 	handlersOfLocality "pet_adopter/src/locality/handlers"
 	logicOfLocality "pet_adopter/src/locality/logic"
 	repoOfLocality "pet_adopter/src/locality/repo"
 
+	handlersOfModeration "pet_adopter/src/moderation/handlers"
+	logicOfModeration "pet_adopter/src/moderation/logic"
+	repoOfModeration "pet_adopter/src/moderation/repo"
+
 	handlersOfRegion "pet_adopter/src/region/handlers"
 	logicOfRegion "pet_adopter/src/region/logic"
 	repoOfRegion "pet_adopter/src/region/repo"
 
+	handlersOfSavedSearch "pet_adopter/src/savedsearch/handlers"
+	logicOfSavedSearch "pet_adopter/src/savedsearch/logic"
+	repoOfSavedSearch "pet_adopter/src/savedsearch/repo"
+
+	"pet_adopter/src/user"
 	handlersOfUser "pet_adopter/src/user/handlers"
 	logicOfUser "pet_adopter/src/user/logic"
 	repoOfUser "pet_adopter/src/user/repo"
+	"pet_adopter/src/utils"
+
+	handlersOfWatch "pet_adopter/src/watch/handlers"
+	logicOfWatch "pet_adopter/src/watch/logic"
+	repoOfWatch "pet_adopter/src/watch/repo"
 )
 
 func init() {
@@ -87,6 +119,19 @@ func main() {
 	cfg := config.MustLoadConfig(os.Getenv("CONFIG_FILE"), logger)
 	logger.Info("Config file loaded")
 
+	if err := utils.ValidateTokenConfig(cfg.Session.AccessToken); err != nil {
+		logger.Error(errors.Wrap(err, "invalid access token config").Error())
+		return
+	}
+	if err := utils.ValidateTokenConfig(cfg.Session.RefreshToken); err != nil {
+		logger.Error(errors.Wrap(err, "invalid refresh token config").Error())
+		return
+	}
+	if err := utils.ValidatePasswordConfig(cfg.Password); err != nil {
+		logger.Error(errors.Wrap(err, "invalid password config").Error())
+		return
+	}
+
 	postgres, err := pgxpool.Connect(context.Background(), os.Getenv("POSTGRES_URL"))
 	if err != nil {
 		logger.Error(errors.Wrap(err, "failed to connect to postgres").Error())
@@ -113,41 +158,96 @@ func main() {
 	}
 	logger.Info("Redis connected")
 
+	auditRepo := repoOfAudit.NewAuditPostgres(postgres)
+	auditLogic := logicOfAudit.NewAuditLogic(auditRepo)
+	auditHandler := handlersOfAudit.NewAuditHandler(&auditLogic, cfg.Ad)
+
 	animalRepo := repoOfAnimal.NewAnimalPostgres(postgres)
-	animalLogic := logicOfAnimal.NewAnimalLogic(animalRepo)
+	animalLogic := logicOfAnimal.NewAnimalLogic(animalRepo, &auditLogic)
 	animalHandler := handlersOfAnimal.NewAnimalHandler(&animalLogic)
 
 	breedRepo := repoOfBreed.NewBreedPostgres(postgres)
-	breedLogic := logicOfBreed.NewBreedLogic(breedRepo)
+	breedLogic := logicOfBreed.NewBreedLogic(breedRepo, &auditLogic)
 	breedHandler := handlersOfBreed.NewBreedHandler(&breedLogic)
 
 	regionRepo := repoOfRegion.NewRegionPostgres(postgres)
-	regionLogic := logicOfRegion.NewRegionLogic(regionRepo)
+	regionLogic := logicOfRegion.NewRegionLogic(regionRepo, &auditLogic)
 	regionHandler := handlersOfRegion.NewRegionHandler(&regionLogic)
 
 	localityRepo := repoOfLocality.NewLocalityPostgres(postgres)
-	localityLogic := logicOfLocality.NewLocalityLogic(localityRepo)
+	localityLogic := logicOfLocality.NewLocalityLogic(localityRepo, &auditLogic)
 	localityHandler := handlersOfLocality.NewLocalityHandler(&localityLogic)
 
 	sessionRepo := repoOfUser.NewSessionRedis(redisClient)
 	sessionLogic := logicOfUser.NewSessionLogic(sessionRepo, cfg.Session)
 
 	userRepo := repoOfUser.NewUserPostgres(postgres)
-	userLogic := logicOfUser.NewUserLogic(userRepo, localityRepo)
+	userLogic := logicOfUser.NewUserLogic(userRepo, localityRepo, &auditLogic, cfg.Password)
 	userHandler := handlersOfUser.NewUserHandler(userLogic, sessionLogic, &localityLogic, cfg.Session, cfg.Validation)
 
-	adRepo := repoOfAd.NewAdPostgres(postgres)
-	adLogic := logicOfAd.NewAdLogic(adRepo, userRepo, animalRepo, breedRepo, localityRepo)
+	photoStorage, err := storageOfAd.NewPhotoStorage(cfg.Storage)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to create photo storage").Error())
+		return
+	}
 
+	adRepo := repoOfAd.NewAdPostgres(postgres)
 	chatGPTClient := request.NewChatGPTClient(cfg.ChatGPT)
+	moderationRepo := repoOfModeration.NewModerationPostgres(postgres)
+
+	var screener ad.Screener
+	if cfg.Ad.Moderation.PrePublication {
+		screener, err = logicOfModeration.NewScreener(moderationRepo, adRepo, chatGPTClient, &auditLogic, cfg.Ad)
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to create ad screener").Error())
+			return
+		}
+	}
+
+	adLogic := logicOfAd.NewAdLogic(adRepo, userRepo, animalRepo, breedRepo, localityRepo, photoStorage, screener, &auditLogic, cfg.Ad)
+
 	chaGPTRepo := chatGPTRepo.NewDescriptionPostgres(postgres)
 	chatGPT := logic.NewChatGPT(chatGPTClient, chaGPTRepo, adRepo, *cfg)
 
-	adHandler := handlersOfAd.NewAdHandler(&adLogic, userLogic, &localityLogic, chatGPT, cfg.Ad)
+	watchRepo := repoOfWatch.NewWatchPostgres(postgres)
+	watchLogic := logicOfWatch.NewWatchLogic(watchRepo, adRepo)
+	watchHandler := handlersOfWatch.NewWatchHandler(&watchLogic, cfg.Ad)
+
+	adHandler := handlersOfAd.NewAdHandler(&adLogic, userLogic, &localityLogic, chatGPT, &watchLogic, cfg.Ad)
+
+	favoriteRepo := repoOfFavorite.NewFavoritePostgres(postgres)
+	favoriteLogic := logicOfFavorite.NewFavoriteLogic(favoriteRepo, adRepo)
+	favoriteHandler := handlersOfFavorite.NewFavoriteHandler(&favoriteLogic, cfg.Ad)
+
+	conversationRepo := repoOfConversation.NewConversationPostgres(postgres)
+	conversationLogic := logicOfConversation.NewConversationLogic(conversationRepo, adRepo)
+	conversationHandler := handlersOfConversation.NewConversationHandler(&conversationLogic, cfg.Ad)
+
+	applicationRepo := repoOfApplication.NewApplicationPostgres(postgres)
+	applicationLogic := logicOfApplication.NewApplicationLogic(applicationRepo, adRepo, &auditLogic)
+	applicationHandler := handlersOfApplication.NewApplicationHandler(&applicationLogic, cfg.Ad)
+
+	moderationLogic := logicOfModeration.NewModerationLogic(moderationRepo, adRepo, &auditLogic, cfg.Ad)
+	moderationHandler := handlersOfModeration.NewModerationHandler(&moderationLogic, cfg.Ad)
+
+	savedSearchRepo := repoOfSavedSearch.NewSavedSearchPostgres(postgres)
+	savedSearchLogic := logicOfSavedSearch.NewSavedSearchLogic(savedSearchRepo, adRepo, userRepo, localityRepo, cfg.SavedSearch, cfg.Ad)
+	savedSearchHandler := handlersOfSavedSearch.NewSavedSearchHandler(&savedSearchLogic, cfg.Ad)
+
+	workerCtx, stopWorkers := context.WithCancel(context.WithValue(context.Background(), config.LoggerContextKey, logger))
+	defer stopWorkers()
+
+	go savedSearchLogic.Run(workerCtx)
+	logger.Info("Saved search worker started")
 
 	reqIDMiddleware := middleware.CreateRequestIDMiddleware(logger)
 	sessionMiddlewareNeedAuth := middleware.CreateSessionMiddleware(userLogic, sessionLogic, cfg.Session, true)
 	sessionMiddlewareNoAuth := middleware.CreateSessionMiddleware(userLogic, sessionLogic, cfg.Session, false)
+	canManageCatalog := middleware.CreatePermissionMiddleware(user.PermManageCatalog)
+	canDeleteAds := middleware.CreatePermissionMiddleware(user.PermDeleteAds)
+	canManageRoles := middleware.CreatePermissionMiddleware(user.PermManageRoles)
+	canModerateAds := middleware.CreatePermissionMiddleware(user.PermModerateAds)
+	canViewAudit := middleware.CreatePermissionMiddleware(user.PermViewAudit)
 
 	r := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
 	r.Use(
@@ -172,19 +272,43 @@ func main() {
 			Methods(http.MethodPost, http.MethodOptions)
 		user.Handle("/login", http.HandlerFunc(userHandler.Login)).
 			Methods(http.MethodPost, http.MethodOptions)
+		user.Handle("/refresh", http.HandlerFunc(userHandler.Refresh)).
+			Methods(http.MethodPost, http.MethodOptions)
 		user.Handle("/logout", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.Logout))).
 			Methods(http.MethodPost, http.MethodOptions)
 		user.Handle("", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.GetUser))).
 			Methods(http.MethodGet, http.MethodOptions)
+		user.Handle("/sessions", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.GetSessions))).
+			Methods(http.MethodGet, http.MethodOptions)
+		user.Handle("/sessions/{id}/revoke", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.RevokeSession))).
+			Methods(http.MethodPost, http.MethodOptions)
+		user.Handle("/sessions/revoke_others", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.RevokeOtherSessions))).
+			Methods(http.MethodPost, http.MethodOptions)
 		user.Handle("/set_locality", sessionMiddlewareNeedAuth(http.HandlerFunc(userHandler.SetLocality))).
 			Methods(http.MethodPost, http.MethodOptions)
+		user.Handle("/favorites", sessionMiddlewareNeedAuth(http.HandlerFunc(favoriteHandler.GetFavorites))).
+			Methods(http.MethodGet, http.MethodOptions)
+		user.Handle("/recently_viewed", sessionMiddlewareNeedAuth(http.HandlerFunc(watchHandler.GetRecentlyViewed))).
+			Methods(http.MethodGet, http.MethodOptions)
+		user.Handle("/searches", sessionMiddlewareNeedAuth(http.HandlerFunc(savedSearchHandler.GetSavedSearches))).
+			Methods(http.MethodGet, http.MethodOptions)
+		user.Handle("/searches/save", sessionMiddlewareNeedAuth(http.HandlerFunc(savedSearchHandler.Create))).
+			Methods(http.MethodPost, http.MethodOptions)
+		user.Handle("/searches/{id}/remove", sessionMiddlewareNeedAuth(http.HandlerFunc(savedSearchHandler.Remove))).
+			Methods(http.MethodPost, http.MethodOptions)
+		user.Handle("/searches/notifications", sessionMiddlewareNeedAuth(http.HandlerFunc(savedSearchHandler.GetNotifications))).
+			Methods(http.MethodGet, http.MethodOptions)
 	}
 
 	ads := r.PathPrefix("/ads").Subrouter()
 	{
 		ads.Handle("", sessionMiddlewareNoAuth(http.HandlerFunc(adHandler.Search))).
 			Methods(http.MethodGet, http.MethodOptions)
-		ads.Handle("/{id}", http.HandlerFunc(adHandler.Get)).
+		ads.Handle("/facets", sessionMiddlewareNoAuth(http.HandlerFunc(adHandler.Facets))).
+			Methods(http.MethodGet, http.MethodOptions)
+		ads.Handle("/map", sessionMiddlewareNoAuth(http.HandlerFunc(adHandler.Map))).
+			Methods(http.MethodGet, http.MethodOptions)
+		ads.Handle("/{id}", sessionMiddlewareNoAuth(http.HandlerFunc(adHandler.Get))).
 			Methods(http.MethodGet, http.MethodOptions)
 		ads.Handle("/{id}/same", http.HandlerFunc(adHandler.GetSame)).
 			Methods(http.MethodGet, http.MethodOptions)
@@ -194,9 +318,54 @@ func main() {
 			Methods(http.MethodPost, http.MethodOptions)
 		ads.Handle("/{id}/update_photo", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.UpdatePhoto))).
 			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/photos/add", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.AddPhotos))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/photos/remove", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.RemovePhoto))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/photos/reorder", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.ReorderPhotos))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/photos/cover", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.SetCoverPhoto))).
+			Methods(http.MethodPost, http.MethodOptions)
 		ads.Handle("/{id}/close", sessionMiddlewareNeedAuth(http.HandlerFunc(adHandler.Close))).
 			Methods(http.MethodPost, http.MethodOptions)
-		ads.Handle("/{id}/delete", middleware.AdminMiddleware(http.HandlerFunc(adHandler.Delete))).
+		ads.Handle("/{id}/delete", sessionMiddlewareNeedAuth(canDeleteAds(http.HandlerFunc(adHandler.Delete)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/favorite", sessionMiddlewareNeedAuth(http.HandlerFunc(favoriteHandler.Add))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/favorite/remove", sessionMiddlewareNeedAuth(http.HandlerFunc(favoriteHandler.Remove))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/thread", sessionMiddlewareNeedAuth(http.HandlerFunc(conversationHandler.OpenThread))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/report", sessionMiddlewareNeedAuth(http.HandlerFunc(moderationHandler.Report))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/apply", sessionMiddlewareNeedAuth(http.HandlerFunc(applicationHandler.Apply))).
+			Methods(http.MethodPost, http.MethodOptions)
+		ads.Handle("/{id}/applications", sessionMiddlewareNeedAuth(http.HandlerFunc(applicationHandler.GetAdApplications))).
+			Methods(http.MethodGet, http.MethodOptions)
+	}
+
+	r.Handle("/photos/{name}", http.HandlerFunc(adHandler.GetPhoto)).
+		Methods(http.MethodGet, http.MethodOptions)
+
+	applications := r.PathPrefix("/applications").Subrouter()
+	{
+		applications.Handle("", sessionMiddlewareNeedAuth(http.HandlerFunc(applicationHandler.GetHistory))).
+			Methods(http.MethodGet, http.MethodOptions)
+		applications.Handle("/{id}/accept", sessionMiddlewareNeedAuth(http.HandlerFunc(applicationHandler.Accept))).
+			Methods(http.MethodPost, http.MethodOptions)
+		applications.Handle("/{id}/reject", sessionMiddlewareNeedAuth(http.HandlerFunc(applicationHandler.Reject))).
+			Methods(http.MethodPost, http.MethodOptions)
+	}
+
+	threads := r.PathPrefix("/threads").Subrouter()
+	{
+		threads.Handle("", sessionMiddlewareNeedAuth(http.HandlerFunc(conversationHandler.GetThreads))).
+			Methods(http.MethodGet, http.MethodOptions)
+		threads.Handle("/{id}/messages", sessionMiddlewareNeedAuth(http.HandlerFunc(conversationHandler.GetMessages))).
+			Methods(http.MethodGet, http.MethodOptions)
+		threads.Handle("/{id}/send", sessionMiddlewareNeedAuth(http.HandlerFunc(conversationHandler.SendMessage))).
+			Methods(http.MethodPost, http.MethodOptions)
+		threads.Handle("/{id}/close", sessionMiddlewareNeedAuth(http.HandlerFunc(conversationHandler.CloseThread))).
 			Methods(http.MethodPost, http.MethodOptions)
 	}
 
@@ -206,9 +375,9 @@ func main() {
 			Methods(http.MethodGet, http.MethodOptions)
 		animals.Handle("/{id}", http.HandlerFunc(animalHandler.GetAnimalByID)).
 			Methods(http.MethodGet, http.MethodOptions)
-		animals.Handle("/add", middleware.AdminMiddleware(http.HandlerFunc(animalHandler.AddAnimal))).
+		animals.Handle("/add", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(animalHandler.AddAnimal)))).
 			Methods(http.MethodPost, http.MethodOptions)
-		animals.Handle("/remove", middleware.AdminMiddleware(http.HandlerFunc(animalHandler.RemoveAnimalByID))).
+		animals.Handle("/remove", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(animalHandler.RemoveAnimalByID)))).
 			Methods(http.MethodPost, http.MethodOptions)
 	}
 
@@ -218,9 +387,9 @@ func main() {
 			Methods(http.MethodGet, http.MethodOptions)
 		breeds.Handle("/{id}", http.HandlerFunc(breedHandler.GetBreedByID)).
 			Methods(http.MethodGet, http.MethodOptions)
-		breeds.Handle("/add", middleware.AdminMiddleware(http.HandlerFunc(breedHandler.AddBreed))).
+		breeds.Handle("/add", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(breedHandler.AddBreed)))).
 			Methods(http.MethodPost, http.MethodOptions)
-		breeds.Handle("/remove", middleware.AdminMiddleware(http.HandlerFunc(breedHandler.RemoveBreedByID))).
+		breeds.Handle("/remove", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(breedHandler.RemoveBreedByID)))).
 			Methods(http.MethodPost, http.MethodOptions)
 	}
 
@@ -230,9 +399,9 @@ func main() {
 			Methods(http.MethodGet, http.MethodOptions)
 		regions.Handle("/{id}", http.HandlerFunc(regionHandler.GetRegionByID)).
 			Methods(http.MethodGet, http.MethodOptions)
-		regions.Handle("/add", middleware.AdminMiddleware(http.HandlerFunc(regionHandler.AddRegion))).
+		regions.Handle("/add", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(regionHandler.AddRegion)))).
 			Methods(http.MethodPost, http.MethodOptions)
-		regions.Handle("/remove", middleware.AdminMiddleware(http.HandlerFunc(regionHandler.RemoveRegionByID))).
+		regions.Handle("/remove", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(regionHandler.RemoveRegionByID)))).
 			Methods(http.MethodPost, http.MethodOptions)
 	}
 
@@ -242,12 +411,42 @@ func main() {
 			Methods(http.MethodGet, http.MethodOptions)
 		localities.Handle("/{id}", http.HandlerFunc(localityHandler.GetLocalityByID)).
 			Methods(http.MethodGet, http.MethodOptions)
-		localities.Handle("/add", middleware.AdminMiddleware(http.HandlerFunc(localityHandler.AddLocality))).
+		localities.Handle("/add", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(localityHandler.AddLocality)))).
 			Methods(http.MethodPost, http.MethodOptions)
-		localities.Handle("/remove", middleware.AdminMiddleware(http.HandlerFunc(localityHandler.RemoveLocalityByID))).
+		localities.Handle("/remove", sessionMiddlewareNeedAuth(canManageCatalog(http.HandlerFunc(localityHandler.RemoveLocalityByID)))).
 			Methods(http.MethodPost, http.MethodOptions)
 	}
 
+	moderationRoutes := r.PathPrefix("/moderation").Subrouter()
+	{
+		moderationRoutes.Handle("/queue", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.GetQueue)))).
+			Methods(http.MethodGet, http.MethodOptions)
+		moderationRoutes.Handle("/pending", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.GetPending)))).
+			Methods(http.MethodGet, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/approve", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.Approve)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/reports", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.GetAdReports)))).
+			Methods(http.MethodGet, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/hide", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.Hide)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/unhide", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.Unhide)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/block", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.Block)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		moderationRoutes.Handle("/ads/{id}/dismiss", sessionMiddlewareNeedAuth(canModerateAds(http.HandlerFunc(moderationHandler.Dismiss)))).
+			Methods(http.MethodPost, http.MethodOptions)
+	}
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	{
+		admin.Handle("/staff", sessionMiddlewareNeedAuth(canManageRoles(http.HandlerFunc(userHandler.GetStaff)))).
+			Methods(http.MethodGet, http.MethodOptions)
+		admin.Handle("/users/{username}/set_role", sessionMiddlewareNeedAuth(canManageRoles(http.HandlerFunc(userHandler.SetRole)))).
+			Methods(http.MethodPost, http.MethodOptions)
+		admin.Handle("/audit", sessionMiddlewareNeedAuth(canViewAudit(http.HandlerFunc(auditHandler.GetEntries)))).
+			Methods(http.MethodGet, http.MethodOptions)
+	}
+
 	http.Handle("/", r)
 	server := http.Server{
 		Handler:           middleware.PathMiddleware(r),
@@ -271,6 +470,8 @@ func main() {
 	sig := <-signalCh
 	logger.Info("Received signal: " + sig.String())
 
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Main.ShutdownTimeout)
 	defer cancel()
 
@@ -3,31 +3,74 @@ package ad
 import (
 	"context"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/satori/uuid"
 	"pet_adopter/src/config"
+	"pet_adopter/src/user"
+	"pet_adopter/src/utils"
 )
 
 var (
 	ErrAdNotFound        = errors.New("ad not found")
 	ErrNotOwner          = errors.New("not owner")
 	ErrInvalidForeignKey = errors.New("invalid foreign key")
+	ErrPhotoNotFound     = errors.New("photo not found")
+	ErrTooManyPhotos     = errors.New("too many photos")
+	ErrLastPhoto         = errors.New("can not remove the last photo")
+	ErrInvalidPhotoOrder = errors.New("invalid photo order")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidLocation   = errors.New("invalid location")
+	ErrInvalidMapArea    = errors.New("invalid map area")
+	ErrModerated         = errors.New("ad is hidden or blocked by a moderator")
+	ErrPhotosChanged     = errors.New("photos of the ad were changed concurrently")
 )
 
 const (
 	Actual    = "A"
 	Realised  = "R"
 	Cancelled = "C"
+
+	// Hidden and Blocked ads are set by moderators, Pending ads wait for pre-publication moderation.
+	// They are only visible to their owners and moderators.
+	Hidden  = "H"
+	Blocked = "B"
+	Pending = "P"
+)
+
+var ModeratedStatuses = []string{Hidden, Blocked, Pending}
+
+// IsVisible tells whether the ad can be shown to the user of ctx, moderated ads are seen only by their owners and moderators.
+func IsVisible(ctx context.Context, info Ad) bool {
+	if !slices.Contains(ModeratedStatuses, info.Status) {
+		return true
+	}
+
+	return info.OwnerID == utils.GetUserIDFromContext(ctx) || user.HasPermission(utils.GetUserRoleFromContext(ctx), user.PermModerateAds)
+}
+
+const (
+	SortRelevance = "relevance"
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortDistance  = "distance"
 )
 
+var SortOrders = []string{SortRelevance, SortNewest, SortOldest, SortPriceAsc, SortPriceDesc, SortDistance}
+
 type Ad struct {
 	ID uuid.UUID `json:"id"`
 
 	OwnerID uuid.UUID `json:"owner_id"`
 	Status  string    `json:"status"`
 
+	// ModerationReason explains to the owner why the ad is Hidden, Blocked or still Pending.
+	ModerationReason string `json:"moderation_reason,omitempty"`
+
 	AdForm
 
 	CreatedAt time.Time `json:"created_at"`
@@ -44,6 +87,18 @@ type AdForm struct {
 	BreedID     uuid.UUID `json:"breed_id"`
 	Price       int       `json:"price"`
 	Contacts    string    `json:"contacts"`
+
+	// LocalityID and the coordinates are optional, an ad without them is placed at the owner's locality.
+	LocalityID *uuid.UUID `json:"locality_id,omitempty"`
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+}
+
+// Location replaces all the location fields of an ad at once, nil fields are cleared.
+type Location struct {
+	LocalityID *uuid.UUID `json:"locality_id,omitempty"`
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
 }
 
 type UpdateForm struct {
@@ -54,44 +109,165 @@ type UpdateForm struct {
 	BreedID     *uuid.UUID `json:"breed_id,omitempty"`
 	Price       *int       `json:"price,omitempty"`
 	Contacts    *string    `json:"contacts,omitempty"`
+	Location    *Location  `json:"location,omitempty"`
 	Status      *string    `json:"status,omitempty"`
 }
 
 type PhotoParams struct {
 	Data      io.ReadSeeker `json:"data"`
+	Medium    io.ReadSeeker `json:"medium"`
+	Thumbnail io.ReadSeeker `json:"thumbnail"`
 	Extension string        `json:"extension"`
 }
 
+type Photo struct {
+	ID           uuid.UUID `json:"id"`
+	AdID         uuid.UUID `json:"ad_id"`
+	URL          string    `json:"url"`
+	MediumURL    string    `json:"medium_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Position     int       `json:"position"`
+	IsCover      bool      `json:"is_cover"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type AdInfo struct {
 	Username     string `json:"username"`
 	AnimalName   string `json:"animal_name"`
 	BreedName    string `json:"breed_name"`
 	LocalityName string `json:"locality_name"`
+	Views        int    `json:"views"`
+	UniqueViews  int    `json:"unique_views"`
+
+	// Latitude and Longitude are where the ad is shown: its own coordinates or its locality's.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
 }
 
 type RespAd struct {
-	Info      Ad     `json:"info"`
-	ExtraInfo AdInfo `json:"extra_info"`
+	Info      Ad      `json:"info"`
+	ExtraInfo AdInfo  `json:"extra_info"`
+	Photos    []Photo `json:"photos"`
+
+	IsFavorite     bool `json:"is_favorite"`
+	FavoritesCount int  `json:"favorites_count"`
+
+	Highlight *Highlight `json:"highlight,omitempty"`
+
+	// Distance is the distance in km from the search centre, set when the search has one and the ad has a location.
+	Distance *float64 `json:"distance,omitempty"`
+
+	// SortKey is the numeric value (score, price or distance) the ad was ordered by in SearchAds, nil for time-only orders
+	// and for the ads without a distance.
+	SortKey *float64 `json:"-"`
+}
+
+// Highlight holds title and description snippets with the words matched by SearchParams.Query wrapped in <b> tags.
+// The snippets are HTML-escaped ad text, they are safe to render as HTML.
+type Highlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
 }
 
 type SearchParams struct {
+	Query    *string    `json:"query,omitempty"`
 	OwnerID  *uuid.UUID `json:"owner_id"`
 	AnimalID *uuid.UUID `json:"animal_id"`
 	BreedID  *uuid.UUID `json:"breed_id"`
 	MinPrice *int       `json:"min_price"`
 	MaxPrice *int       `json:"max_price"`
 	Radius   *int       `json:"radius"`
+	RegionID *uuid.UUID `json:"region_id,omitempty"`
+
+	// Latitude and Longitude or LocalityID set the centre for Radius and SortDistance,
+	// the user's locality is used when none of them is given.
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	LocalityID *uuid.UUID `json:"locality_id,omitempty"`
 
 	AllStatuses bool `json:"all_statuses"`
+	// PublishedAfter keeps the ads that became Actual after it, also the ones approved or unhidden later.
+	PublishedAfter *time.Time `json:"published_after,omitempty"`
+
+	// IncludeModerated lets AllStatuses return ModeratedStatuses, it is set only for the owner's own listing.
+	// Statuses overrides both and is used by moderators.
+	IncludeModerated bool     `json:"-"`
+	Statuses         []string `json:"-"`
+
+	// Sort is one of SortOrders, SortRelevance falls back to updated_at when there is no history and no Query.
+	Sort string `json:"sort,omitempty"`
 
 	Limit  int `json:"limit"`
 	Offset int `json:"offset"`
+
+	// Cursor replaces Offset when set, WithTotal requests the number of ads matching the filters.
+	Cursor    *SearchCursor `json:"-"`
+	WithTotal bool          `json:"-"`
+}
+
+// SearchCursor points right after the last ad of a page. Best keeps the history the first page was ranked with,
+// so later pages are ordered the same way even if the history was updated in between.
+type SearchCursor struct {
+	Sort      string    `json:"sort,omitempty"`
+	Best      *History  `json:"best,omitempty"`
+	Key       *float64  `json:"key,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID `json:"id"`
+}
+
+type SearchResult struct {
+	Ads        []RespAd `json:"ads"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Total      *int     `json:"total,omitempty"`
+}
+
+type FacetValue struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+}
+
+// PriceBucket counts ads with Min <= price < Max, a nil bound is open.
+type PriceBucket struct {
+	Min   *int `json:"min"`
+	Max   *int `json:"max"`
+	Count int  `json:"count"`
+}
+
+type Facets struct {
+	Total      int           `json:"total"`
+	Animals    []FacetValue  `json:"animals"`
+	Breeds     []FacetValue  `json:"breeds"`
+	Localities []FacetValue  `json:"localities"`
+	Regions    []FacetValue  `json:"regions"`
+	Prices     []PriceBucket `json:"prices"`
+}
+
+// MapArea is the visible part of the map, an area crossing the antimeridian must be requested as two areas.
+type MapArea struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+	Zoom         int     `json:"zoom"`
+}
+
+// MapCluster groups the ads of one grid cell or locality, LocalityID is set only for locality clusters.
+// Latitude and Longitude are the centroid of the ads, AdIDs holds up to the configured sample of the newest ones.
+type MapCluster struct {
+	LocalityID *uuid.UUID  `json:"locality_id,omitempty"`
+	Count      int         `json:"count"`
+	Latitude   float64     `json:"latitude"`
+	Longitude  float64     `json:"longitude"`
+	AdIDs      []uuid.UUID `json:"ad_ids"`
 }
 
 type SearchExtra struct {
-	Latitude  float64  `json:"latitude"`
-	Longitude float64  `json:"longitude"`
-	Best      *History `json:"best"`
+	HasLocation bool     `json:"has_location"`
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	Best        *History `json:"best"`
 }
 
 type History struct {
@@ -106,34 +282,68 @@ type History struct {
 
 func NewSearchParams(cfg config.AdConfig) SearchParams {
 	return SearchParams{
-		OwnerID:     nil,
-		AnimalID:    nil,
-		BreedID:     nil,
-		MinPrice:    nil,
-		MaxPrice:    nil,
-		Radius:      nil,
-		AllStatuses: false,
-		Limit:       cfg.DefaultSearchLimit,
-		Offset:      cfg.DefaultSearchOffset,
+		Query:          nil,
+		OwnerID:        nil,
+		AnimalID:       nil,
+		BreedID:        nil,
+		MinPrice:       nil,
+		MaxPrice:       nil,
+		Radius:         nil,
+		RegionID:       nil,
+		Latitude:       nil,
+		Longitude:      nil,
+		LocalityID:     nil,
+		AllStatuses:    false,
+		PublishedAfter: nil,
+		Sort:           SortRelevance,
+		Limit:          cfg.DefaultSearchLimit,
+		Offset:         cfg.DefaultSearchOffset,
 	}
 }
 
 type AdRepo interface {
 	SearchAds(ctx context.Context, params SearchParams, extra SearchExtra) ([]RespAd, error)
+	CountAds(ctx context.Context, params SearchParams, extra SearchExtra) (int, error)
+	GetFacets(ctx context.Context, params SearchParams, extra SearchExtra, priceBounds []int) (Facets, error)
+	GetMapClusters(ctx context.Context, params SearchParams, extra SearchExtra, area MapArea, cellSize float64, sampleSize int, limit int) ([]MapCluster, error)
 	SaveHistory(ctx context.Context, row History) error
 	GetHistory(ctx context.Context, userID uuid.UUID) (*History, error)
 	GetAd(ctx context.Context, id uuid.UUID) (RespAd, error)
-	CreateAd(ctx context.Context, ad Ad) error
+	GetAdsByIDs(ctx context.Context, ids []uuid.UUID) ([]RespAd, error)
+	CreateAd(ctx context.Context, ad Ad, photos []Photo) error
 	UpdateAd(ctx context.Context, id uuid.UUID, form UpdateForm, now time.Time) error
 	DeleteAd(ctx context.Context, id uuid.UUID) error
+	AddPhotos(ctx context.Context, adID uuid.UUID, photos []Photo) error
+	ReplaceCoverPhoto(ctx context.Context, oldCoverID uuid.UUID, photo Photo) error
+	RemovePhoto(ctx context.Context, adID uuid.UUID, photoID uuid.UUID, newCoverID uuid.UUID, now time.Time) error
+	ReorderPhotos(ctx context.Context, adID uuid.UUID, photoIDs []uuid.UUID) error
+	SetCoverPhoto(ctx context.Context, adID uuid.UUID, photoID uuid.UUID, now time.Time) error
+}
+
+// Screener runs the pre-publication checks of a Pending ad and publishes it when they pass.
+type Screener interface {
+	ScreenAd(ctx context.Context, id uuid.UUID) error
+}
+
+type PhotoStorage interface {
+	SavePhoto(ctx context.Context, name string, data io.ReadSeeker) error
+	GetPhoto(ctx context.Context, name string) ([]byte, error)
+	RemovePhoto(ctx context.Context, name string) error
 }
 
 type AdLogic interface {
-	SearchAds(ctx context.Context, params SearchParams, extra SearchExtra) ([]RespAd, error)
+	SearchAds(ctx context.Context, params SearchParams, extra SearchExtra) (SearchResult, error)
+	GetFacets(ctx context.Context, params SearchParams, extra SearchExtra) (Facets, error)
+	GetMapClusters(ctx context.Context, params SearchParams, extra SearchExtra, area MapArea) ([]MapCluster, error)
 	GetAd(ctx context.Context, id uuid.UUID) (RespAd, error)
-	CreateAd(ctx context.Context, form AdForm, photoForm PhotoParams) (RespAd, error)
+	CreateAd(ctx context.Context, form AdForm, photoForms []PhotoParams) (RespAd, error)
 	UpdateAd(ctx context.Context, id uuid.UUID, form UpdateForm) (RespAd, error)
 	UpdatePhoto(ctx context.Context, id uuid.UUID, photoForm PhotoParams) (RespAd, error)
+	AddPhotos(ctx context.Context, id uuid.UUID, photoForms []PhotoParams) (RespAd, error)
+	RemovePhoto(ctx context.Context, id uuid.UUID, photoID uuid.UUID) (RespAd, error)
+	ReorderPhotos(ctx context.Context, id uuid.UUID, photoIDs []uuid.UUID) (RespAd, error)
+	SetCoverPhoto(ctx context.Context, id uuid.UUID, photoID uuid.UUID) (RespAd, error)
+	GetPhotoData(ctx context.Context, photoURL string) (PhotoParams, error)
 	Close(ctx context.Context, id uuid.UUID, status string) (RespAd, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
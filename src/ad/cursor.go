@@ -0,0 +1,32 @@
+package ad
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+func EncodeCursor(cursor SearchCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal cursor")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func DecodeCursor(value string) (SearchCursor, error) {
+	var cursor SearchCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	goerrors "errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -19,6 +25,7 @@ import (
 	"pet_adopter/src/locality"
 	"pet_adopter/src/user"
 	"pet_adopter/src/utils"
+	"pet_adopter/src/watch"
 )
 
 type AdHandler struct {
@@ -26,21 +33,25 @@ type AdHandler struct {
 	userLogic     user.UserLogic
 	localityLogic locality.LocalityLogic
 	chatGPT       chatgpt.ChatGPT
+	watchLogic    watch.WatchLogic
 	cfg           config.AdConfig
 }
 
-func NewAdHandler(logic ad.AdLogic, userLogic user.UserLogic, localityLogic locality.LocalityLogic, chatGPT chatgpt.ChatGPT, cfg config.AdConfig) *AdHandler {
+func NewAdHandler(logic ad.AdLogic, userLogic user.UserLogic, localityLogic locality.LocalityLogic, chatGPT chatgpt.ChatGPT, watchLogic watch.WatchLogic, cfg config.AdConfig) *AdHandler {
 	return &AdHandler{
 		logic:         logic,
 		userLogic:     userLogic,
 		localityLogic: localityLogic,
 		chatGPT:       chatGPT,
+		watchLogic:    watchLogic,
 		cfg:           cfg,
 	}
 }
 
 type SearchResponse struct {
-	Ads []ad.RespAd `json:"ads"`
+	Ads        []ad.RespAd `json:"ads"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      *int        `json:"total,omitempty"`
 }
 
 func (h *AdHandler) Search(w http.ResponseWriter, r *http.Request) {
@@ -53,35 +64,177 @@ func (h *AdHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	searchExtra, err := h.getSearchExtra(ctx, &searchParams)
+	if err != nil {
+		handleSearchExtraError(ctx, w, err)
+		return
+	}
+
+	found, err := h.logic.SearchAds(ctx, searchParams, searchExtra)
+	if err != nil {
+		if goerrors.Is(err, ad.ErrInvalidCursor) {
+			utils.LogError(ctx, err, "invalid cursor")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return
+		}
+		utils.LogError(ctx, err, "failed to search ads")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	result := SearchResponse{
+		Ads:        found.Ads,
+		NextCursor: found.NextCursor,
+		Total:      found.Total,
+	}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(r.Context(), err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+// getSearchExtra resolves the centre for radius and distance searches: the given coordinates, the given locality
+// or the user's locality. Radius and distance sort are dropped when the centre is unknown.
+func (h *AdHandler) getSearchExtra(ctx context.Context, searchParams *ad.SearchParams) (ad.SearchExtra, error) {
 	searchExtra := ad.SearchExtra{}
-	if searchParams.Radius != nil {
+	if searchParams.Radius == nil && searchParams.Sort != ad.SortDistance {
+		return searchExtra, nil
+	}
+
+	switch {
+	case searchParams.Latitude != nil:
+		searchExtra.HasLocation = true
+		searchExtra.Latitude = *searchParams.Latitude
+		searchExtra.Longitude = *searchParams.Longitude
+	case searchParams.LocalityID != nil:
+		loc, err := h.localityLogic.GetLocalityByID(ctx, *searchParams.LocalityID)
+		if err != nil {
+			return searchExtra, errors.Wrap(err, "failed to get search locality")
+		}
+		searchExtra.HasLocation = true
+		searchExtra.Latitude = loc.Latitude
+		searchExtra.Longitude = loc.Longitude
+	default:
 		userID := utils.GetUserIDFromContext(ctx)
+		if userID == uuid.Nil {
+			break
+		}
+
 		userInfo, err := h.userLogic.GetUserByID(ctx, userID)
-		if err == nil {
-			loc, err := h.localityLogic.GetLocalityByID(ctx, userInfo.LocalityID)
-			if err == nil {
-				searchExtra.Latitude = loc.Latitude
-				searchExtra.Longitude = loc.Longitude
-			} else {
-				utils.LogError(ctx, err, "failed to get user location")
-				searchParams.Radius = nil
-			}
-		} else {
+		if err != nil {
 			utils.LogError(ctx, err, fmt.Sprintf("failed to get user by ID=%s", userID.String()))
-			searchParams.Radius = nil
+			break
+		}
+
+		loc, err := h.localityLogic.GetLocalityByID(ctx, userInfo.LocalityID)
+		if err != nil {
+			utils.LogError(ctx, err, "failed to get user location")
+			break
+		}
+		searchExtra.HasLocation = true
+		searchExtra.Latitude = loc.Latitude
+		searchExtra.Longitude = loc.Longitude
+	}
+
+	if !searchExtra.HasLocation {
+		searchParams.Radius = nil
+		if searchParams.Sort == ad.SortDistance {
+			searchParams.Sort = ad.SortRelevance
 		}
 	}
 
-	foundAds, err := h.logic.SearchAds(ctx, searchParams, searchExtra)
+	return searchExtra, nil
+}
+
+// handleSearchExtraError answers a failed getSearchExtra, an unknown locality_id is the client's mistake.
+func handleSearchExtraError(ctx context.Context, w http.ResponseWriter, err error) {
+	if goerrors.Is(err, locality.ErrLocalityNotFound) {
+		utils.LogError(ctx, err, "search locality not found")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+	utils.LogError(ctx, err, "failed to resolve search centre")
+	http.Error(w, utils.Internal, http.StatusInternalServerError)
+}
+
+type FacetsResponse struct {
+	Facets ad.Facets `json:"facets"`
+}
+
+func (h *AdHandler) Facets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	searchParams, err := getSearchParamsFromQuery(r.URL.Query(), h.cfg)
 	if err != nil {
-		utils.LogError(ctx, err, "failed to search ads")
+		utils.LogError(ctx, err, "failed to parse search params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	searchExtra, err := h.getSearchExtra(ctx, &searchParams)
+	if err != nil {
+		handleSearchExtraError(ctx, w, err)
+		return
+	}
+
+	facets, err := h.logic.GetFacets(ctx, searchParams, searchExtra)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to get facets")
 		http.Error(w, utils.Internal, http.StatusInternalServerError)
 		return
 	}
 
-	result := SearchResponse{Ads: foundAds}
+	result := FacetsResponse{Facets: facets}
 	if err = json.NewEncoder(w).Encode(result); err != nil {
-		utils.LogError(r.Context(), err, utils.MsgErrMarshalResponse)
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type MapResponse struct {
+	Clusters []ad.MapCluster `json:"clusters"`
+}
+
+func (h *AdHandler) Map(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	searchParams, err := getSearchParamsFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse search params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	area, err := getMapAreaFromQuery(r.URL.Query())
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse map area")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	searchExtra, err := h.getSearchExtra(ctx, &searchParams)
+	if err != nil {
+		handleSearchExtraError(ctx, w, err)
+		return
+	}
+
+	clusters, err := h.logic.GetMapClusters(ctx, searchParams, searchExtra, area)
+	if err != nil {
+		if goerrors.Is(err, ad.ErrInvalidMapArea) {
+			utils.LogError(ctx, err, "invalid map area")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return
+		}
+		utils.LogError(ctx, err, "failed to get map clusters")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	result := MapResponse{Clusters: clusters}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
 		http.Error(w, utils.Internal, http.StatusInternalServerError)
 		return
 	}
@@ -107,6 +260,18 @@ func (h *AdHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID := utils.GetUserIDFromContext(ctx)
+	if userID != foundAd.Info.OwnerID {
+		go func() {
+			newCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := h.watchLogic.RecordView(newCtx, userID, adID); err != nil {
+				utils.LogError(ctx, err, "failed to record view")
+			}
+		}()
+	}
+
 	result := GetResponse{Ad: foundAd}
 	if err = json.NewEncoder(w).Encode(result); err != nil {
 		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
@@ -163,6 +328,37 @@ func (h *AdHandler) GetSame(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetPhoto serves a photo by its stored name through the photo storage, nginx falls back to it for
+// the photos missing on its local disk, e.g. every photo of the s3 storage.
+func (h *AdHandler) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := mux.Vars(r)["name"]
+	if name == "" || path.Base(name) != name || strings.HasPrefix(name, ".") {
+		utils.LogErrorMessage(ctx, "invalid photo name")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	photo, err := h.logic.GetPhotoData(ctx, name)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	contentType := mime.TypeByExtension(photo.Extension)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Photo names are never reused, a stored photo does not change.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if _, err = io.Copy(w, photo.Data); err != nil {
+		utils.LogError(ctx, err, "failed to write photo")
+	}
+}
+
 type CreateResponse struct {
 	Ad ad.RespAd `json:"ad"`
 }
@@ -170,8 +366,8 @@ type CreateResponse struct {
 func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	photoData := h.getPhotoDataFromRequest(w, r)
-	if photoData == nil {
+	photosData := h.getPhotosDataFromRequest(w, r, h.cfg.AdPhotoConfig.MaxPhotosPerAd)
+	if photosData == nil {
 		return
 	}
 
@@ -183,7 +379,7 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdAd, err := h.logic.CreateAd(ctx, adForm, *photoData)
+	createdAd, err := h.logic.CreateAd(ctx, adForm, photosData)
 	if err != nil {
 		handleAdError(ctx, w, err)
 		return
@@ -193,7 +389,7 @@ func (h *AdHandler) Create(w http.ResponseWriter, r *http.Request) {
 		newCtx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		if err = h.chatGPT.DescribePhoto(newCtx, createdAd.Info.ID, *photoData, false); err != nil {
+		if err = h.chatGPT.DescribePhoto(newCtx, createdAd.Info.ID, photosData[0], false); err != nil {
 			utils.LogError(ctx, err, "failed to describe photo")
 		}
 	}()
@@ -261,12 +457,12 @@ func (h *AdHandler) UpdatePhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	photoData := h.getPhotoDataFromRequest(w, r)
-	if photoData == nil {
+	photosData := h.getPhotosDataFromRequest(w, r, 1)
+	if photosData == nil {
 		return
 	}
 
-	updatedAd, err := h.logic.UpdatePhoto(ctx, adID, *photoData)
+	updatedAd, err := h.logic.UpdatePhoto(ctx, adID, photosData[0])
 	if err != nil {
 		handleAdError(ctx, w, err)
 		return
@@ -276,7 +472,7 @@ func (h *AdHandler) UpdatePhoto(w http.ResponseWriter, r *http.Request) {
 		newCtx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		if err = h.chatGPT.DescribePhoto(newCtx, updatedAd.Info.ID, *photoData, true); err != nil {
+		if err = h.chatGPT.DescribePhoto(newCtx, updatedAd.Info.ID, photosData[0], true); err != nil {
 			utils.LogError(ctx, err, "failed to describe photo")
 		}
 	}()
@@ -289,6 +485,164 @@ func (h *AdHandler) UpdatePhoto(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type PhotosResponse struct {
+	Ad ad.RespAd `json:"ad"`
+}
+
+func (h *AdHandler) AddPhotos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	photosData := h.getPhotosDataFromRequest(w, r, h.cfg.AdPhotoConfig.MaxPhotosPerAd)
+	if photosData == nil {
+		return
+	}
+
+	currentAd, err := h.logic.GetAd(ctx, adID)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	updatedAd, err := h.logic.AddPhotos(ctx, adID, photosData)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	h.describeCoverIfChanged(ctx, currentAd, updatedAd)
+
+	result := PhotosResponse{Ad: updatedAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type PhotoRequest struct {
+	PhotoID uuid.UUID `json:"photo_id"`
+}
+
+func (h *AdHandler) RemovePhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req PhotoRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	currentAd, err := h.logic.GetAd(ctx, adID)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	updatedAd, err := h.logic.RemovePhoto(ctx, adID, req.PhotoID)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	h.describeCoverIfChanged(ctx, currentAd, updatedAd)
+
+	result := PhotosResponse{Ad: updatedAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type ReorderPhotosRequest struct {
+	PhotoIDs []uuid.UUID `json:"photo_ids"`
+}
+
+func (h *AdHandler) ReorderPhotos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req ReorderPhotosRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	updatedAd, err := h.logic.ReorderPhotos(ctx, adID, req.PhotoIDs)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	result := PhotosResponse{Ad: updatedAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdHandler) SetCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req PhotoRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	currentAd, err := h.logic.GetAd(ctx, adID)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	updatedAd, err := h.logic.SetCoverPhoto(ctx, adID, req.PhotoID)
+	if err != nil {
+		handleAdError(ctx, w, err)
+		return
+	}
+
+	h.describeCoverIfChanged(ctx, currentAd, updatedAd)
+
+	result := PhotosResponse{Ad: updatedAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
 type CloseRequest struct {
 	Status string `json:"status"`
 }
@@ -358,6 +712,38 @@ func (h *AdHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const maxTextQueryLength = 256
+
+// getMapAreaFromQuery parses the required min_lat, max_lat, min_lon, max_lon and zoom parameters.
+func getMapAreaFromQuery(query url.Values) (ad.MapArea, error) {
+	result := ad.MapArea{}
+
+	coords := []struct {
+		name  string
+		value *float64
+	}{
+		{"min_lat", &result.MinLatitude},
+		{"max_lat", &result.MaxLatitude},
+		{"min_lon", &result.MinLongitude},
+		{"max_lon", &result.MaxLongitude},
+	}
+	for _, coord := range coords {
+		value, err := strconv.ParseFloat(query.Get(coord.name), 64)
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to parse %s", coord.name)
+		}
+		*coord.value = value
+	}
+
+	zoom, err := strconv.Atoi(query.Get("zoom"))
+	if err != nil {
+		return result, errors.Wrap(err, "failed to parse zoom")
+	}
+	result.Zoom = zoom
+
+	return result, nil
+}
+
 func getSearchParamsFromQuery(query url.Values, cfg config.AdConfig) (ad.SearchParams, error) {
 	result := ad.NewSearchParams(cfg)
 
@@ -370,6 +756,16 @@ func getSearchParamsFromQuery(query url.Values, cfg config.AdConfig) (ad.SearchP
 		result.AllStatuses = allStatuses
 	}
 
+	textQuery := strings.TrimSpace(query.Get("q"))
+	if textQuery == "" {
+		result.Query = nil
+	} else {
+		if utf8.RuneCountInString(textQuery) > maxTextQueryLength {
+			return result, fmt.Errorf("q too long, maximum length: %d", maxTextQueryLength)
+		}
+		result.Query = &textQuery
+	}
+
 	animalIDString := query.Get("animal_id")
 	if animalIDString == "" {
 		result.AnimalID = nil
@@ -416,6 +812,47 @@ func getSearchParamsFromQuery(query url.Values, cfg config.AdConfig) (ad.SearchP
 		result.MaxPrice = &maxPrice
 	}
 
+	regionIDString := query.Get("region_id")
+	if regionIDString != "" {
+		regionID, err := uuid.FromString(regionIDString)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse region_id")
+		}
+		result.RegionID = &regionID
+	}
+
+	latString, lonString := query.Get("lat"), query.Get("lon")
+	if (latString == "") != (lonString == "") {
+		return result, errors.New("lat and lon must be given together")
+	}
+	if latString != "" {
+		latitude, err := strconv.ParseFloat(latString, 64)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse lat")
+		}
+		longitude, err := strconv.ParseFloat(lonString, 64)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse lon")
+		}
+		if !utils.ValidCoordinates(latitude, longitude) {
+			return result, fmt.Errorf("invalid coordinates: %f, %f", latitude, longitude)
+		}
+		result.Latitude = &latitude
+		result.Longitude = &longitude
+	}
+
+	localityIDString := query.Get("locality_id")
+	if localityIDString != "" {
+		if result.Latitude != nil {
+			return result, errors.New("lat/lon and locality_id can not be given together")
+		}
+		localityID, err := uuid.FromString(localityIDString)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse locality_id")
+		}
+		result.LocalityID = &localityID
+	}
+
 	radiusString := query.Get("radius")
 	if radiusString == "" {
 		result.Radius = nil
@@ -437,10 +874,40 @@ func getSearchParamsFromQuery(query url.Values, cfg config.AdConfig) (ad.SearchP
 			return result, errors.Wrap(err, "failed to parse limit")
 		}
 		limit := int(limit64)
+		if limit <= 0 {
+			return result, fmt.Errorf("invalid limit: %d", limit)
+		}
 		if limit > cfg.MaxSearchLimit {
 			result.Limit = cfg.MaxSearchLimit
+		} else {
+			result.Limit = limit
+		}
+	}
+
+	sortString := query.Get("sort")
+	if sortString != "" {
+		if !slices.Contains(ad.SortOrders, sortString) {
+			return result, fmt.Errorf("invalid sort: %s", sortString)
+		}
+		result.Sort = sortString
+	}
+
+	cursorString := query.Get("cursor")
+	if cursorString != "" {
+		cursor, err := ad.DecodeCursor(cursorString)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse cursor")
+		}
+		result.Cursor = &cursor
+	}
+
+	withTotalString := query.Get("with_total")
+	if withTotalString != "" {
+		withTotal, err := strconv.ParseBool(withTotalString)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse with_total")
 		}
-		result.Limit = limit
+		result.WithTotal = withTotal
 	}
 
 	offsetString := query.Get("offset")
@@ -483,11 +950,18 @@ func getUpdateFormFromRequest(req UpdateRequest) ad.UpdateForm {
 	if fieldsToUpdateMap["contacts"] {
 		result.Contacts = &req.Form.Contacts
 	}
+	if fieldsToUpdateMap["location"] {
+		result.Location = &ad.Location{
+			LocalityID: req.Form.LocalityID,
+			Latitude:   req.Form.Latitude,
+			Longitude:  req.Form.Longitude,
+		}
+	}
 
 	return result
 }
 
-func (h *AdHandler) getPhotoDataFromRequest(w http.ResponseWriter, r *http.Request) *ad.PhotoParams {
+func (h *AdHandler) getPhotosDataFromRequest(w http.ResponseWriter, r *http.Request, maxFiles int) []ad.PhotoParams {
 	ctx := r.Context()
 
 	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.AdPhotoConfig.MaxFormDataSize)
@@ -505,39 +979,83 @@ func (h *AdHandler) getPhotoDataFromRequest(w http.ResponseWriter, r *http.Reque
 	}()
 
 	files := r.MultipartForm.File[h.cfg.AdPhotoConfig.RequestFieldName]
-	if len(files) > 1 {
-		utils.LogError(ctx, goerrors.New("multipart form contains multiple files"), "failed to add multiple files")
+	if len(files) == 0 {
+		utils.LogError(ctx, goerrors.New("multipart form contains no files"), "failed to get photo file")
 		http.Error(w, utils.Invalid, http.StatusBadRequest)
 		return nil
 	}
-
-	photoFile, _, err := r.FormFile(h.cfg.AdPhotoConfig.RequestFieldName)
-	if err != nil {
-		utils.LogError(ctx, err, "failed to get photo file")
+	if len(files) > maxFiles {
+		utils.LogError(ctx, goerrors.New("multipart form contains too many files"), "failed to add files")
 		http.Error(w, utils.Invalid, http.StatusBadRequest)
 		return nil
 	}
 
-	content, err := io.ReadAll(photoFile)
-	if err != nil && !goerrors.Is(err, io.EOF) {
-		if goerrors.As(err, new(*http.MaxBytesError)) {
-			utils.LogError(ctx, err, "failed to read file content, too large")
-			http.Error(w, utils.Invalid, http.StatusRequestEntityTooLarge)
+	result := make([]ad.PhotoParams, 0, len(files))
+	for _, fileHeader := range files {
+		photoFile, err := fileHeader.Open()
+		if err != nil {
+			utils.LogError(ctx, err, "failed to get photo file")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
 			return nil
 		}
-	}
 
-	photoFileExtension := utils.GetFormat(h.cfg.AdPhotoConfig.FileTypes, content)
-	if photoFileExtension == "" {
-		utils.LogError(ctx, goerrors.New("unknown file extension"), "failed to get file format")
-		http.Error(w, utils.Invalid, http.StatusBadRequest)
-		return nil
+		content, err := io.ReadAll(photoFile)
+		photoFile.Close()
+		if err != nil && !goerrors.Is(err, io.EOF) {
+			if goerrors.As(err, new(*http.MaxBytesError)) {
+				utils.LogError(ctx, err, "failed to read file content, too large")
+				http.Error(w, utils.Invalid, http.StatusRequestEntityTooLarge)
+				return nil
+			}
+			utils.LogError(ctx, err, "failed to read file content")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return nil
+		}
+
+		photoFileExtension := utils.GetFormat(h.cfg.AdPhotoConfig.FileTypes, content)
+		if photoFileExtension == "" {
+			utils.LogError(ctx, goerrors.New("unknown file extension"), "failed to get file format")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return nil
+		}
+
+		processed, err := utils.ProcessImage(content, h.cfg.AdPhotoConfig.Processing)
+		if err != nil {
+			utils.LogError(ctx, err, "failed to process photo")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return nil
+		}
+
+		result = append(result, ad.PhotoParams{
+			Data:      bytes.NewReader(processed.Full),
+			Medium:    bytes.NewReader(processed.Medium),
+			Thumbnail: bytes.NewReader(processed.Thumbnail),
+			Extension: processed.Extension,
+		})
 	}
 
-	return &ad.PhotoParams{
-		Data:      photoFile,
-		Extension: photoFileExtension,
+	return result
+}
+
+func (h *AdHandler) describeCoverIfChanged(ctx context.Context, before ad.RespAd, after ad.RespAd) {
+	if before.Info.PhotoURL == after.Info.PhotoURL {
+		return
 	}
+
+	go func() {
+		newCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cover, err := h.logic.GetPhotoData(newCtx, after.Info.PhotoURL)
+		if err != nil {
+			utils.LogError(ctx, err, "failed to get cover photo")
+			return
+		}
+
+		if err = h.chatGPT.DescribePhoto(newCtx, after.Info.ID, cover, before.Info.PhotoURL != ""); err != nil {
+			utils.LogError(ctx, err, "failed to describe photo")
+		}
+	}()
 }
 
 func handleAdError(ctx context.Context, w http.ResponseWriter, err error) {
@@ -551,6 +1069,21 @@ func handleAdError(ctx context.Context, w http.ResponseWriter, err error) {
 	case goerrors.Is(err, ad.ErrInvalidForeignKey):
 		utils.LogError(ctx, err, "invalid foreign key")
 		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	case goerrors.Is(err, ad.ErrInvalidLocation):
+		utils.LogError(ctx, err, "invalid location")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	case goerrors.Is(err, ad.ErrModerated):
+		utils.LogError(ctx, err, "ad is moderated")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	case goerrors.Is(err, ad.ErrPhotoNotFound):
+		utils.LogError(ctx, err, "photo not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, ad.ErrTooManyPhotos), goerrors.Is(err, ad.ErrLastPhoto), goerrors.Is(err, ad.ErrInvalidPhotoOrder):
+		utils.LogError(ctx, err, "invalid photo operation")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	case goerrors.Is(err, ad.ErrPhotosChanged):
+		utils.LogError(ctx, err, "photos changed concurrently")
+		http.Error(w, utils.Invalid, http.StatusConflict)
 	default:
 		utils.LogError(ctx, err, "failed to perform operation")
 		http.Error(w, utils.Internal, http.StatusInternalServerError)
@@ -1,18 +1,22 @@
 package logic
 
 import (
+	"bytes"
 	"context"
 	goerrors "errors"
 	"fmt"
-	"os"
+	"math"
 	"path"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/satori/uuid"
 	"pet_adopter/src/ad"
 	"pet_adopter/src/animal"
+	"pet_adopter/src/audit"
 	"pet_adopter/src/breed"
+	"pet_adopter/src/config"
 	"pet_adopter/src/locality"
 	"pet_adopter/src/user"
 	"pet_adopter/src/utils"
@@ -24,26 +28,37 @@ type AdLogic struct {
 	animalRepo   animal.AnimalRepo
 	breedRepo    breed.BreedRepo
 	localityRepo locality.LocalityRepo
+	storage      ad.PhotoStorage
+	screener     ad.Screener
+	auditLog     audit.AuditLogic
+	cfg          config.AdConfig
 }
 
-func NewAdLogic(repo ad.AdRepo, userRepo user.UserRepo, animalRepo animal.AnimalRepo, breedRepo breed.BreedRepo, localityRepo locality.LocalityRepo) AdLogic {
+// NewAdLogic creates the ad logic, screener is nil unless pre-publication moderation is enabled.
+func NewAdLogic(repo ad.AdRepo, userRepo user.UserRepo, animalRepo animal.AnimalRepo, breedRepo breed.BreedRepo, localityRepo locality.LocalityRepo, storage ad.PhotoStorage, screener ad.Screener, auditLog audit.AuditLogic, cfg config.AdConfig) AdLogic {
 	return AdLogic{
 		repo:         repo,
 		userRepo:     userRepo,
 		animalRepo:   animalRepo,
 		breedRepo:    breedRepo,
 		localityRepo: localityRepo,
+		storage:      storage,
+		screener:     screener,
+		auditLog:     auditLog,
+		cfg:          cfg,
 	}
 }
 
-func (l *AdLogic) SearchAds(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) ([]ad.RespAd, error) {
+func (l *AdLogic) SearchAds(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) (ad.SearchResult, error) {
 	var (
 		best *ad.History
 		err  error
 	)
 
 	userID := utils.GetUserIDFromContext(ctx)
-	if userID != uuid.Nil {
+	if params.Cursor != nil {
+		best = params.Cursor.Best
+	} else if userID != uuid.Nil {
 		best, err = l.repo.GetHistory(ctx, userID)
 		if err != nil {
 			utils.LogError(ctx, err, "failed to get history")
@@ -52,12 +67,41 @@ func (l *AdLogic) SearchAds(ctx context.Context, params ad.SearchParams, extra a
 	}
 	extra.Best = best
 
+	params.IncludeModerated = params.OwnerID != nil && *params.OwnerID == userID
+
 	resp, err := l.repo.SearchAds(ctx, params, extra)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to search ads")
+		return ad.SearchResult{}, errors.Wrap(err, "failed to search ads")
+	}
+
+	result := ad.SearchResult{Ads: resp}
+
+	if len(resp) > 0 && len(resp) == params.Limit {
+		last := resp[len(resp)-1]
+		cursor := ad.SearchCursor{
+			Sort:      params.Sort,
+			Best:      best,
+			Key:       last.SortKey,
+			CreatedAt: last.Info.CreatedAt,
+			UpdatedAt: last.Info.UpdatedAt,
+			ID:        last.Info.ID,
+		}
+
+		result.NextCursor, err = ad.EncodeCursor(cursor)
+		if err != nil {
+			return ad.SearchResult{}, errors.Wrap(err, "failed to encode cursor")
+		}
 	}
 
-	if userID != uuid.Nil && (params.AnimalID != nil || params.BreedID != nil || params.MinPrice != nil || params.MaxPrice != nil || params.Radius != nil) {
+	if params.WithTotal {
+		total, err := l.repo.CountAds(ctx, params, extra)
+		if err != nil {
+			return ad.SearchResult{}, errors.Wrap(err, "failed to count ads")
+		}
+		result.Total = &total
+	}
+
+	if userID != uuid.Nil && params.Cursor == nil && (params.AnimalID != nil || params.BreedID != nil || params.MinPrice != nil || params.MaxPrice != nil || params.Radius != nil) {
 		go func() {
 			row := ad.History{
 				UserID:    userID,
@@ -80,43 +124,86 @@ func (l *AdLogic) SearchAds(ctx context.Context, params ad.SearchParams, extra a
 		}()
 	}
 
-	return resp, nil
+	return result, nil
+}
+
+func (l *AdLogic) GetFacets(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) (ad.Facets, error) {
+	return l.repo.GetFacets(ctx, params, extra, l.cfg.PriceBuckets)
+}
+
+func (l *AdLogic) GetMapClusters(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra, area ad.MapArea) ([]ad.MapCluster, error) {
+	mapCfg := l.cfg.Map
+	if area.Zoom < 0 || area.Zoom > mapCfg.MaxZoom ||
+		!utils.ValidCoordinates(area.MinLatitude, area.MinLongitude) || !utils.ValidCoordinates(area.MaxLatitude, area.MaxLongitude) ||
+		area.MinLatitude > area.MaxLatitude || area.MinLongitude > area.MaxLongitude {
+		return nil, ad.ErrInvalidMapArea
+	}
+
+	// A map tile spans 360 / 2^zoom degrees of longitude.
+	cellSize := 0.0
+	if area.Zoom < mapCfg.LocalityZoom {
+		cellSize = 360 / (math.Exp2(float64(area.Zoom)) * float64(mapCfg.GridSize))
+	}
+
+	return l.repo.GetMapClusters(ctx, params, extra, area, cellSize, mapCfg.SampleSize, mapCfg.MaxClusters)
 }
 
 func (l *AdLogic) GetAd(ctx context.Context, id uuid.UUID) (ad.RespAd, error) {
-	return l.repo.GetAd(ctx, id)
+	result, err := l.repo.GetAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if !ad.IsVisible(ctx, result.Info) {
+		return ad.RespAd{}, ad.ErrAdNotFound
+	}
+
+	return result, nil
 }
 
-func (l *AdLogic) CreateAd(ctx context.Context, form ad.AdForm, photoForm ad.PhotoParams) (ad.RespAd, error) {
+func (l *AdLogic) CreateAd(ctx context.Context, form ad.AdForm, photoForms []ad.PhotoParams) (ad.RespAd, error) {
+	if len(photoForms) > l.cfg.AdPhotoConfig.MaxPhotosPerAd {
+		return ad.RespAd{}, ad.ErrTooManyPhotos
+	}
+
+	if err := l.validateLocation(ctx, ad.Location{LocalityID: form.LocalityID, Latitude: form.Latitude, Longitude: form.Longitude}); err != nil {
+		return ad.RespAd{}, err
+	}
+
 	now := time.Now().Local()
 	adID := uuid.NewV4()
 
-	photoBasePath := os.Getenv("PHOTO_BASE_PATH")
-	photoFilename := adID.String()
-
-	if err := utils.WriteFileOnDisk(
-		path.Join(photoBasePath, photoFilename),
-		photoForm.Extension,
-		photoForm.Data,
-	); err != nil {
-		return ad.RespAd{}, errors.Wrap(err, "failed to write photo on disk")
+	photos, err := l.savePhotos(ctx, adID, photoForms, 0, now)
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to save photos")
 	}
+	photos[0].IsCover = true
+
+	form.PhotoURL = photos[0].URL
 
-	form.PhotoURL = photoFilename + photoForm.Extension
+	status := ad.Actual
+	if l.screener != nil {
+		status = ad.Pending
+	}
 
 	result := ad.Ad{
 		ID:        adID,
 		OwnerID:   utils.GetUserIDFromContext(ctx),
-		Status:    ad.Actual,
+		Status:    status,
 		AdForm:    form,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if err := l.repo.CreateAd(ctx, result); err != nil {
+	if err = l.repo.CreateAd(ctx, result, photos); err != nil {
+		l.removeStoredPhotos(ctx, photos)
 		return ad.RespAd{}, errors.Wrap(err, "failed to create ad")
 	}
 
+	if status == ad.Pending {
+		l.screenAd(ctx, adID)
+	}
+
 	return l.repo.GetAd(ctx, adID)
 }
 
@@ -132,6 +219,23 @@ func (l *AdLogic) UpdateAd(ctx context.Context, id uuid.UUID, form ad.UpdateForm
 		return ad.RespAd{}, ad.ErrNotOwner
 	}
 
+	if form.Status != nil && slices.Contains(ad.ModeratedStatuses, currentAd.Info.Status) {
+		return ad.RespAd{}, ad.ErrModerated
+	}
+
+	// An edited ad goes through pre-publication moderation again.
+	screen := l.screener != nil && form.Status == nil && (currentAd.Info.Status == ad.Actual || currentAd.Info.Status == ad.Pending)
+	if screen {
+		pending := ad.Pending
+		form.Status = &pending
+	}
+
+	if form.Location != nil {
+		if err = l.validateLocation(ctx, *form.Location); err != nil {
+			return ad.RespAd{}, err
+		}
+	}
+
 	if err = l.repo.UpdateAd(ctx, id, form, now); err != nil {
 		if goerrors.Is(err, ad.ErrInvalidForeignKey) {
 			return ad.RespAd{}, ad.ErrInvalidForeignKey
@@ -139,45 +243,196 @@ func (l *AdLogic) UpdateAd(ctx context.Context, id uuid.UUID, form ad.UpdateForm
 		return ad.RespAd{}, errors.Wrap(err, "failed to update ad")
 	}
 
-	return l.repo.GetAd(ctx, id)
+	// The result is read before screening starts, a quick screening would hide the move to Pending from the audit log.
+	result, err := l.repo.GetAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if result.Info.Status != currentAd.Info.Status {
+		l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityAd, id, currentAd.Info, result.Info)
+	}
+
+	if screen {
+		l.screenAd(ctx, id)
+	}
+
+	return result, nil
 }
 
 func (l *AdLogic) UpdatePhoto(ctx context.Context, id uuid.UUID, photoForm ad.PhotoParams) (ad.RespAd, error) {
 	now := time.Now().Local()
 
-	currentAd, err := l.repo.GetAd(ctx, id)
+	currentAd, err := l.getOwnAd(ctx, id)
 	if err != nil {
-		return ad.RespAd{}, errors.Wrap(err, "failed to check owner")
+		return ad.RespAd{}, err
 	}
 
-	if currentAd.Info.OwnerID != utils.GetUserIDFromContext(ctx) {
-		return ad.RespAd{}, ad.ErrNotOwner
+	cover, hasCover := findCoverPhoto(currentAd.Photos)
+
+	// Without a cover the new photo is added to the gallery instead of replacing one.
+	position := nextPhotoPosition(currentAd.Photos)
+	photosCount := len(currentAd.Photos) + 1
+	if hasCover {
+		position = cover.Position
+		photosCount--
+	}
+
+	if photosCount > l.cfg.AdPhotoConfig.MaxPhotosPerAd {
+		return ad.RespAd{}, ad.ErrTooManyPhotos
+	}
+
+	photos, err := l.savePhotos(ctx, id, []ad.PhotoParams{photoForm}, position, now)
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to save new photo")
+	}
+
+	photos[0].IsCover = true
+
+	if err = l.repo.ReplaceCoverPhoto(ctx, cover.ID, photos[0]); err != nil {
+		l.removeStoredPhotos(ctx, photos)
+		return ad.RespAd{}, errors.Wrap(err, "failed to replace cover photo")
+	}
+
+	if hasCover {
+		l.removeStoredPhoto(ctx, cover)
+	}
+
+	return l.repo.GetAd(ctx, id)
+}
+
+func (l *AdLogic) AddPhotos(ctx context.Context, id uuid.UUID, photoForms []ad.PhotoParams) (ad.RespAd, error) {
+	now := time.Now().Local()
+
+	currentAd, err := l.getOwnAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if len(currentAd.Photos)+len(photoForms) > l.cfg.AdPhotoConfig.MaxPhotosPerAd {
+		return ad.RespAd{}, ad.ErrTooManyPhotos
+	}
+
+	photos, err := l.savePhotos(ctx, id, photoForms, nextPhotoPosition(currentAd.Photos), now)
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to save photos")
+	}
+
+	if err = l.repo.AddPhotos(ctx, id, photos); err != nil {
+		l.removeStoredPhotos(ctx, photos)
+		return ad.RespAd{}, errors.Wrap(err, "failed to add photos")
+	}
+
+	if _, hasCover := findCoverPhoto(currentAd.Photos); !hasCover {
+		err = l.repo.SetCoverPhoto(ctx, id, photos[0].ID, now)
+	} else {
+		err = l.repo.UpdateAd(ctx, id, ad.UpdateForm{}, now)
+	}
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to update ad")
+	}
+
+	return l.repo.GetAd(ctx, id)
+}
+
+func (l *AdLogic) RemovePhoto(ctx context.Context, id uuid.UUID, photoID uuid.UUID) (ad.RespAd, error) {
+	now := time.Now().Local()
+
+	currentAd, err := l.getOwnAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	photo, found := findPhoto(currentAd.Photos, photoID)
+	if !found {
+		return ad.RespAd{}, ad.ErrPhotoNotFound
+	}
+
+	if len(currentAd.Photos) == 1 {
+		return ad.RespAd{}, ad.ErrLastPhoto
+	}
+
+	newCoverID := uuid.Nil
+	if photo.IsCover {
+		for _, next := range currentAd.Photos {
+			if next.ID != photoID {
+				newCoverID = next.ID
+				break
+			}
+		}
+	}
+
+	if err = l.repo.RemovePhoto(ctx, id, photoID, newCoverID, now); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to remove photo")
 	}
 
-	photoBasePath := os.Getenv("PHOTO_BASE_PATH")
-	photoFilename := id.String()
+	l.removeStoredPhoto(ctx, photo)
 
-	if err = os.Remove(path.Join(photoBasePath, currentAd.Info.PhotoURL)); err != nil {
-		return ad.RespAd{}, errors.Wrap(err, "failed to remove old photo from disk")
+	return l.repo.GetAd(ctx, id)
+}
+
+func (l *AdLogic) ReorderPhotos(ctx context.Context, id uuid.UUID, photoIDs []uuid.UUID) (ad.RespAd, error) {
+	now := time.Now().Local()
+
+	currentAd, err := l.getOwnAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
 	}
 
-	if err = utils.WriteFileOnDisk(
-		path.Join(photoBasePath, photoFilename),
-		photoForm.Extension,
-		photoForm.Data,
-	); err != nil {
-		return ad.RespAd{}, errors.Wrap(err, "failed to write new photo on disk")
+	if len(photoIDs) != len(currentAd.Photos) {
+		return ad.RespAd{}, ad.ErrInvalidPhotoOrder
 	}
 
-	newPhotoURL := photoFilename + photoForm.Extension
+	seen := make(map[uuid.UUID]bool, len(photoIDs))
+	for _, photoID := range photoIDs {
+		if _, found := findPhoto(currentAd.Photos, photoID); !found || seen[photoID] {
+			return ad.RespAd{}, ad.ErrInvalidPhotoOrder
+		}
+		seen[photoID] = true
+	}
+
+	if err = l.repo.ReorderPhotos(ctx, id, photoIDs); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to reorder photos")
+	}
 
-	if err = l.repo.UpdateAd(ctx, id, ad.UpdateForm{PhotoURL: &newPhotoURL}, now); err != nil {
+	if err = l.repo.UpdateAd(ctx, id, ad.UpdateForm{}, now); err != nil {
 		return ad.RespAd{}, errors.Wrap(err, "failed to update ad")
 	}
 
 	return l.repo.GetAd(ctx, id)
 }
 
+func (l *AdLogic) SetCoverPhoto(ctx context.Context, id uuid.UUID, photoID uuid.UUID) (ad.RespAd, error) {
+	now := time.Now().Local()
+
+	currentAd, err := l.getOwnAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if _, found := findPhoto(currentAd.Photos, photoID); !found {
+		return ad.RespAd{}, ad.ErrPhotoNotFound
+	}
+
+	if err = l.repo.SetCoverPhoto(ctx, id, photoID, now); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to set cover photo")
+	}
+
+	return l.repo.GetAd(ctx, id)
+}
+
+func (l *AdLogic) GetPhotoData(ctx context.Context, photoURL string) (ad.PhotoParams, error) {
+	data, err := l.storage.GetPhoto(ctx, photoURL)
+	if err != nil {
+		return ad.PhotoParams{}, errors.Wrap(err, "failed to get photo from storage")
+	}
+
+	return ad.PhotoParams{
+		Data:      bytes.NewReader(data),
+		Extension: path.Ext(photoURL),
+	}, nil
+}
+
 func (l *AdLogic) Close(ctx context.Context, id uuid.UUID, status string) (ad.RespAd, error) {
 	now := time.Now().Local()
 
@@ -190,11 +445,24 @@ func (l *AdLogic) Close(ctx context.Context, id uuid.UUID, status string) (ad.Re
 		return ad.RespAd{}, ad.ErrNotOwner
 	}
 
+	if slices.Contains(ad.ModeratedStatuses, currentAd.Info.Status) {
+		return ad.RespAd{}, ad.ErrModerated
+	}
+
 	if err = l.repo.UpdateAd(ctx, id, ad.UpdateForm{Status: &status}, now); err != nil {
 		return ad.RespAd{}, errors.Wrap(err, "failed to update ad")
 	}
 
-	return l.repo.GetAd(ctx, id)
+	result, err := l.repo.GetAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if result.Info.Status != currentAd.Info.Status {
+		l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityAd, id, currentAd.Info, result.Info)
+	}
+
+	return result, nil
 }
 
 func (l *AdLogic) Delete(ctx context.Context, id uuid.UUID) error {
@@ -203,11 +471,163 @@ func (l *AdLogic) Delete(ctx context.Context, id uuid.UUID) error {
 		return errors.Wrap(err, "failed to check owner")
 	}
 
-	photoBasePath := os.Getenv("PHOTO_BASE_PATH")
+	if err = l.repo.DeleteAd(ctx, id); err != nil {
+		return err
+	}
+
+	// The files go after the rows, a storage failure leaves an orphan file instead of an ad with broken photos.
+	l.removeStoredPhotos(ctx, currentAd.Photos)
+
+	l.auditLog.Record(ctx, audit.ActionDelete, audit.EntityAd, id, currentAd.Info, nil)
+	return nil
+}
+
+// screenAd runs the pre-publication checks in the background, an ad failing them waits for a moderator.
+func (l *AdLogic) screenAd(ctx context.Context, id uuid.UUID) {
+	go func() {
+		if err := l.screener.ScreenAd(context.WithoutCancel(ctx), id); err != nil {
+			utils.LogError(ctx, err, fmt.Sprintf("failed to screen ad %s", id))
+		}
+	}()
+}
+
+func (l *AdLogic) validateLocation(ctx context.Context, location ad.Location) error {
+	if (location.Latitude == nil) != (location.Longitude == nil) {
+		return ad.ErrInvalidLocation
+	}
+
+	if location.Latitude != nil && !utils.ValidCoordinates(*location.Latitude, *location.Longitude) {
+		return ad.ErrInvalidLocation
+	}
 
-	if err = os.Remove(path.Join(photoBasePath, currentAd.Info.PhotoURL)); err != nil {
-		return errors.Wrap(err, "failed to remove old photo from disk")
+	if location.LocalityID != nil {
+		if _, err := l.localityRepo.GetLocalityByID(ctx, *location.LocalityID); err != nil {
+			if goerrors.Is(err, locality.ErrLocalityNotFound) {
+				return ad.ErrInvalidForeignKey
+			}
+			return errors.Wrap(err, "failed to get locality")
+		}
 	}
 
-	return l.repo.DeleteAd(ctx, id)
+	return nil
+}
+
+func (l *AdLogic) getOwnAd(ctx context.Context, id uuid.UUID) (ad.RespAd, error) {
+	currentAd, err := l.repo.GetAd(ctx, id)
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to check owner")
+	}
+
+	if currentAd.Info.OwnerID != utils.GetUserIDFromContext(ctx) {
+		return ad.RespAd{}, ad.ErrNotOwner
+	}
+
+	return currentAd, nil
+}
+
+// savePhotos stores every variant of the photos, on failure the ones already stored are removed.
+func (l *AdLogic) savePhotos(ctx context.Context, adID uuid.UUID, photoForms []ad.PhotoParams, firstPosition int, now time.Time) ([]ad.Photo, error) {
+	result := make([]ad.Photo, 0, len(photoForms))
+	for i, photoForm := range photoForms {
+		photo, err := l.savePhoto(ctx, adID, photoForm, firstPosition+i, now)
+		if err != nil {
+			l.removeStoredPhotos(ctx, result)
+			return nil, err
+		}
+
+		result = append(result, photo)
+	}
+
+	return result, nil
+}
+
+func (l *AdLogic) savePhoto(ctx context.Context, adID uuid.UUID, photoForm ad.PhotoParams, position int, now time.Time) (ad.Photo, error) {
+	photoID := uuid.NewV4()
+
+	photo := ad.Photo{
+		ID:        photoID,
+		AdID:      adID,
+		URL:       photoID.String() + photoForm.Extension,
+		Position:  position,
+		CreatedAt: now,
+	}
+	photo.MediumURL = photo.URL
+	photo.ThumbnailURL = photo.URL
+
+	if err := l.storage.SavePhoto(ctx, photo.URL, photoForm.Data); err != nil {
+		return ad.Photo{}, errors.Wrap(err, "failed to save photo to storage")
+	}
+
+	if photoForm.Medium != nil {
+		photo.MediumURL = photoID.String() + "_medium" + photoForm.Extension
+		if err := l.storage.SavePhoto(ctx, photo.MediumURL, photoForm.Medium); err != nil {
+			photo.MediumURL = photo.URL
+			l.removeStoredPhoto(ctx, photo)
+			return ad.Photo{}, errors.Wrap(err, "failed to save medium photo to storage")
+		}
+	}
+
+	if photoForm.Thumbnail != nil {
+		photo.ThumbnailURL = photoID.String() + "_thumbnail" + photoForm.Extension
+		if err := l.storage.SavePhoto(ctx, photo.ThumbnailURL, photoForm.Thumbnail); err != nil {
+			photo.ThumbnailURL = photo.URL
+			l.removeStoredPhoto(ctx, photo)
+			return ad.Photo{}, errors.Wrap(err, "failed to save thumbnail photo to storage")
+		}
+	}
+
+	return photo, nil
+}
+
+func (l *AdLogic) removeStoredPhotos(ctx context.Context, photos []ad.Photo) {
+	for _, photo := range photos {
+		l.removeStoredPhoto(ctx, photo)
+	}
+}
+
+func (l *AdLogic) removeStoredPhoto(ctx context.Context, photo ad.Photo) {
+	for _, photoURL := range photoVariantURLs(photo) {
+		if err := l.storage.RemovePhoto(ctx, photoURL); err != nil && !goerrors.Is(err, ad.ErrPhotoNotFound) {
+			utils.LogError(ctx, err, "failed to remove photo from storage")
+		}
+	}
+}
+
+func findPhoto(photos []ad.Photo, photoID uuid.UUID) (ad.Photo, bool) {
+	for _, photo := range photos {
+		if photo.ID == photoID {
+			return photo, true
+		}
+	}
+	return ad.Photo{}, false
+}
+
+func findCoverPhoto(photos []ad.Photo) (ad.Photo, bool) {
+	for _, photo := range photos {
+		if photo.IsCover {
+			return photo, true
+		}
+	}
+	return ad.Photo{}, false
+}
+
+func nextPhotoPosition(photos []ad.Photo) int {
+	next := 0
+	for _, photo := range photos {
+		if photo.Position >= next {
+			next = photo.Position + 1
+		}
+	}
+	return next
+}
+
+func photoVariantURLs(photo ad.Photo) []string {
+	result := []string{photo.URL}
+	if photo.MediumURL != photo.URL {
+		result = append(result, photo.MediumURL)
+	}
+	if photo.ThumbnailURL != photo.URL && photo.ThumbnailURL != photo.MediumURL {
+		result = append(result, photo.ThumbnailURL)
+	}
+	return result
 }
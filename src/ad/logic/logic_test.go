@@ -0,0 +1,47 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+// searchRepo answers SearchAds with fixed ads, the other methods are not used by AdLogic.SearchAds.
+type searchRepo struct {
+	ad.AdRepo
+	ads []ad.RespAd
+}
+
+func (r *searchRepo) SearchAds(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) ([]ad.RespAd, error) {
+	return r.ads, nil
+}
+
+func TestSearchAdsCursorAfterAdWithoutLocation(t *testing.T) {
+	distance := 1.5
+	near := ad.RespAd{Info: ad.Ad{ID: uuid.NewV4()}, SortKey: &distance}
+	unknown := ad.RespAd{Info: ad.Ad{ID: uuid.NewV4()}}
+
+	l := AdLogic{repo: &searchRepo{ads: []ad.RespAd{near, unknown}}}
+	params := ad.SearchParams{Sort: ad.SortDistance, Limit: 2}
+
+	result, err := l.SearchAds(context.Background(), params, ad.SearchExtra{})
+	if err != nil {
+		t.Fatalf("SearchAds: %v", err)
+	}
+	if result.NextCursor == "" {
+		t.Fatal("NextCursor is empty, want a cursor after a full page")
+	}
+
+	cursor, err := ad.DecodeCursor(result.NextCursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if cursor.Key != nil {
+		t.Errorf("cursor key = %v, want nil for an ad without a location", *cursor.Key)
+	}
+	if cursor.ID != unknown.Info.ID || cursor.Sort != ad.SortDistance {
+		t.Errorf("cursor = %+v, want the last ad sorted by distance", cursor)
+	}
+}
@@ -2,12 +2,14 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	goerrors "errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"pet_adopter/src/ad"
+	"pet_adopter/src/utils"
 
 	"github.com/jackc/pgtype/pgxtype"
 	"github.com/jackc/pgx/v4"
@@ -16,26 +18,149 @@ import (
 )
 
 const (
-	getAd = `
+	selectAdFields = `
 SELECT
-	Ad.id, Ad.owner_id, Ad.status,
+	Ad.id, Ad.owner_id, Ad.status, COALESCE(Ad.moderation_reason, ''),
 	Ad.photo_url, Ad.title, Ad.description, Ad.price, Ad.animal_id, Ad.breed_id, Ad.contacts,
+	Ad.locality_id, Ad.latitude, Ad.longitude,
 	Ad.created_at, Ad.updated_at,
 	MyUser.username,
 	Animal.name AS animal_name,
 	Breed.name AS breed_name,
-	COALESCE(Locality.name, '') AS locality_name
+	COALESCE(Locality.name, '') AS locality_name,
+	COALESCE(Ad.latitude, Locality.latitude) AS latitude,
+	COALESCE(Ad.longitude, Locality.longitude) AS longitude,
+	(SELECT COUNT(*) FROM Favorite WHERE Favorite.ad_id = Ad.id) AS favorites_count,
+	EXISTS(SELECT 1 FROM Favorite WHERE Favorite.ad_id = Ad.id AND Favorite.user_id = $1) AS is_favorite,
+	(SELECT COUNT(*) FROM Watch WHERE Watch.ad_id = Ad.id) AS unique_views,
+	Ad.anonymous_views,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', AdPhoto.id,
+			'ad_id', AdPhoto.ad_id,
+			'url', AdPhoto.url,
+			'medium_url', AdPhoto.medium_url,
+			'thumbnail_url', AdPhoto.thumbnail_url,
+			'position', AdPhoto.position,
+			'is_cover', AdPhoto.is_cover,
+			'created_at', AdPhoto.created_at
+		) ORDER BY AdPhoto.position)
+		FROM AdPhoto
+		WHERE AdPhoto.ad_id = Ad.id
+	), '[]'::json) AS photos
+`
+	fromAd = `
 FROM Ad
 JOIN MyUser ON Ad.owner_id = MyUser.id
 JOIN Animal ON Ad.animal_id = Animal.id
 JOIN Breed ON Ad.breed_id = Breed.id
-LEFT JOIN Locality ON MyUser.locality_id = Locality.id
-WHERE Ad.id = $1;
+LEFT JOIN Locality ON COALESCE(Ad.locality_id, MyUser.locality_id) = Locality.id
 `
+	selectAd = selectAdFields + fromAd
+
+	// moderatedStatuses lists ad.ModeratedStatuses, the ads only their owners and moderators can see.
+	moderatedStatuses = "('H', 'B', 'P')"
+
+	// An ad is placed at its own coordinates, then at its own locality, then at the owner's locality.
+	adLatitude  = "COALESCE(Ad.latitude, Locality.latitude)"
+	adLongitude = "COALESCE(Ad.longitude, Locality.longitude)"
+
+	// adDistance is the haversine distance in km from the ad to the centre given by the two placeholders.
+	// It is inlined instead of calling a plpgsql function so the planner evaluates it only for the rows
+	// passing the bounding box.
+	adDistance = `(2 * 6371 * asin(LEAST(1, sqrt(
+		power(sin(radians(` + adLatitude + ` - $%[1]d) / 2), 2) +
+		cos(radians($%[1]d)) * cos(radians(` + adLatitude + `)) * power(sin(radians(` + adLongitude + ` - $%[2]d) / 2), 2)
+	))))`
+
+	// adInBoundingBox has a branch per source of the ad coordinates, so each one is a range scan of
+	// ad_coordinates_idx or locality_coordinates_idx. Ads without any known location are never inside.
+	adInBoundingBox = `Ad.id IN (
+		SELECT id FROM Ad
+		WHERE latitude BETWEEN $%[1]d AND $%[2]d AND longitude BETWEEN $%[3]d AND $%[4]d
+		UNION ALL
+		SELECT Ad.id FROM Locality
+		JOIN Ad ON Ad.locality_id = Locality.id
+		WHERE Ad.latitude IS NULL AND Locality.latitude BETWEEN $%[1]d AND $%[2]d AND Locality.longitude BETWEEN $%[3]d AND $%[4]d
+		UNION ALL
+		SELECT Ad.id FROM Locality
+		JOIN MyUser ON MyUser.locality_id = Locality.id
+		JOIN Ad ON Ad.owner_id = MyUser.id
+		WHERE Ad.latitude IS NULL AND Ad.locality_id IS NULL AND Locality.latitude BETWEEN $%[1]d AND $%[2]d AND Locality.longitude BETWEEN $%[3]d AND $%[4]d
+	)`
 
-	createAd = "INSERT INTO Ad(id, owner_id, status, photo_url, title, description, price, animal_id, breed_id, contacts, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);"
+	// adDocument must stay in sync with ad_text_search_idx in build/create_tables.sql.
+	adDocument          = `to_tsvector('russian', COALESCE(Ad.title, '') || ' ' || COALESCE(Ad.description, ''))`
+	textQuery           = `websearch_to_tsquery('russian', $%d)`
+	textRank            = `ts_rank_cd(` + adDocument + `, ` + textQuery + `, 32)`
+	titleHeadline       = `ts_headline('russian', ` + escapeHTMLStart + `COALESCE(Ad.title, '')` + escapeHTMLEnd + `, ` + textQuery + `, 'HighlightAll=true')`
+	descriptionHeadline = `ts_headline('russian', ` + escapeHTMLStart + `COALESCE(Ad.description, '')` + escapeHTMLEnd + `, ` + textQuery + `, 'MaxFragments=2, MaxWords=20, MinWords=5')`
+
+	// The ad text is HTML-escaped before highlighting, so the <b> tags added by ts_headline are its only markup.
+	// The parser reads the entities as single tokens, they are never split into fragments or highlighted.
+	escapeHTMLStart = `replace(replace(replace(replace(replace(`
+	escapeHTMLEnd   = `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+
+	// textRankWeight scales the text rank (0..1) against the history score (0..5).
+	textRankWeight = 5
+
+	getAd       = selectAd + "WHERE Ad.id = $2;"
+	getAdsByIDs = selectAd + "WHERE Ad.id = ANY($2::uuid[]) AND Ad.status NOT IN " + moderatedStatuses + ";"
+
+	// The ad and its gallery are written in one statement, an ad is never left without its photos.
+	createAd = `
+WITH new_ad AS (
+	INSERT INTO Ad(id, owner_id, status, photo_url, title, description, price, animal_id, breed_id, contacts, locality_id, latitude, longitude, created_at, updated_at, published_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	RETURNING id
+)
+INSERT INTO AdPhoto(id, ad_id, url, medium_url, thumbnail_url, position, is_cover, created_at)
+SELECT photo.id, new_ad.id, photo.url, photo.medium_url, photo.thumbnail_url, photo.position, photo.is_cover, photo.created_at
+FROM new_ad, ` + photoRows + `;
+`
 	deleteAd = "DELETE FROM Ad WHERE id=$1;"
 
+	photoRows = `unnest($%d::uuid[], $%d::text[], $%d::text[], $%d::text[], $%d::integer[], $%d::boolean[], $%d::timestamptz[])
+	AS photo(id, url, medium_url, thumbnail_url, position, is_cover, created_at)`
+	addPhotos = `
+INSERT INTO AdPhoto(id, ad_id, url, medium_url, thumbnail_url, position, is_cover, created_at)
+SELECT photo.id, $1, photo.url, photo.medium_url, photo.thumbnail_url, photo.position, photo.is_cover, photo.created_at
+FROM ` + photoRows + `;
+`
+	// replaceCoverPhoto puts the new cover in place of the old one, the position constraint is checked
+	// at the end of the statement so both rows may share the position meanwhile.
+	replaceCoverPhoto = `
+WITH removed AS (
+	DELETE FROM AdPhoto WHERE id = $1 AND ad_id = $2
+), added AS (
+	INSERT INTO AdPhoto(id, ad_id, url, medium_url, thumbnail_url, position, is_cover, created_at)
+	VALUES ($3, $2, $4, $5, $6, $7, TRUE, $8)
+)
+UPDATE Ad SET photo_url = $4, updated_at = $8 WHERE id = $2;
+`
+	// removePhoto passes the cover to $3 in the same statement, an ad never stays without a cover.
+	removePhoto = `
+WITH removed AS (
+	DELETE FROM AdPhoto WHERE id = $2 AND ad_id = $1 RETURNING id
+), cover AS (
+	UPDATE AdPhoto SET is_cover = TRUE WHERE id = $3 AND ad_id = $1 AND EXISTS (SELECT 1 FROM removed) RETURNING url
+)
+UPDATE Ad SET photo_url = COALESCE((SELECT url FROM cover), photo_url), updated_at = $4
+WHERE id = $1 AND EXISTS (SELECT 1 FROM removed);
+`
+	reorderPhotos = `
+UPDATE AdPhoto
+SET position = ordered.position - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS ordered(id, position)
+WHERE AdPhoto.id = ordered.id AND AdPhoto.ad_id = $1;
+`
+	setCoverPhoto = `
+WITH cover AS (
+	UPDATE AdPhoto SET is_cover = (id = $2) WHERE ad_id = $1 RETURNING url, is_cover
+)
+UPDATE Ad SET photo_url = (SELECT url FROM cover WHERE is_cover), updated_at = $3 WHERE id = $1;
+`
+
 	getHistory  = "SELECT user_id, animal_id, breed_id, min_price, max_price, radius, created_at FROM History WHERE user_id = $1;"
 	saveHistory = `
 INSERT INTO
@@ -58,152 +183,254 @@ func NewAdPostgres(db pgxtype.Querier) *AdPostgres {
 }
 
 func (repo *AdPostgres) SearchAds(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) ([]ad.RespAd, error) {
-	query := `
-SELECT
-	Ad.id, Ad.owner_id, Ad.status,
-	Ad.photo_url, Ad.title, Ad.description, Ad.price, Ad.animal_id, Ad.breed_id, Ad.contacts,
-	Ad.created_at, Ad.updated_at,
-	MyUser.username,
-	Animal.name AS animal_name,
-	Breed.name AS breed_name,
-	COALESCE(Locality.name, '') AS locality_name,
-	COALESCE(Locality.latitude, NULL) AS locality_latitude,
-	COALESCE(Locality.longitude, NULL) AS locality_longitude
-FROM Ad
-JOIN MyUser ON Ad.owner_id = MyUser.id
-JOIN Animal ON Ad.animal_id = Animal.id
-JOIN Breed ON Ad.breed_id = Breed.id
-LEFT JOIN Locality ON MyUser.locality_id = Locality.id
-`
+	q := newSearchQuery(params, extra, utils.GetUserIDFromContext(ctx))
+	order := q.order(params, extra)
 
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
+	fields := selectAdFields
+	if q.textArgIndex != 0 {
+		fields += ", " + fmt.Sprintf(titleHeadline, q.textArgIndex) + ", " + fmt.Sprintf(descriptionHeadline, q.textArgIndex)
+	}
+	if order.key != "" {
+		fields += fmt.Sprintf(", %s::float8 AS sort_key", order.key)
+	}
+	if q.hasCenter {
+		fields += fmt.Sprintf(", %s::float8 AS distance", q.distance())
+	}
+
+	offset := params.Offset
+	if params.Cursor != nil {
+		if params.Cursor.Sort != params.Sort || !order.validCursorKey(params.Cursor.Key) {
+			return nil, ad.ErrInvalidCursor
+		}
 
-	if !params.AllStatuses {
-		conditions = append(conditions, "Ad.status = 'A'")
+		q.addCursorCondition(order, *params.Cursor)
+		offset = 0
 	}
 
-	if params.OwnerID != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.owner_id=$%d", argIndex))
-		args = append(args, *params.OwnerID)
-		argIndex++
+	query := fields + fromAd + q.where() + order.orderBy()
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d;", q.argIndex, q.argIndex+1)
+	args := append(q.args, params.Limit, offset)
+
+	fmt.Printf("%s\n", query)
+
+	result := make([]ad.RespAd, 0)
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get ads from postgres")
 	}
+	defer rows.Close()
 
-	if params.AnimalID != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.animal_id=$%d", argIndex))
-		args = append(args, *params.AnimalID)
-		argIndex++
+	for rows.Next() {
+		var (
+			highlight ad.Highlight
+			key       *float64
+			distance  *float64
+			dest      []interface{}
+		)
+
+		if q.textArgIndex != 0 {
+			dest = append(dest, &highlight.Title, &highlight.Description)
+		}
+		if order.key != "" {
+			dest = append(dest, &key)
+		}
+		if q.hasCenter {
+			dest = append(dest, &distance)
+		}
+
+		row, err := scanAd(rows, dest...)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse ad")
+		}
+
+		if q.textArgIndex != 0 {
+			row.Highlight = &highlight
+		}
+		if order.key != "" {
+			row.SortKey = key
+		}
+		row.Distance = distance
+		result = append(result, row)
 	}
 
-	if params.BreedID != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.breed_id=$%d", argIndex))
-		args = append(args, *params.BreedID)
-		argIndex++
+	return result, nil
+}
+
+func (repo *AdPostgres) CountAds(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra) (int, error) {
+	q := newSearchQuery(params, extra)
+
+	var count int
+	if err := repo.db.QueryRow(ctx, "SELECT COUNT(*) "+fromAd+q.where()+";", q.args...).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "failed to count ads in postgres")
 	}
 
-	if params.MinPrice != nil && params.MaxPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.price BETWEEN $%d AND $%d", argIndex, argIndex+1))
-		args = append(args, *params.MinPrice, *params.MaxPrice)
-		argIndex += 2
-	} else if params.MinPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.price >= $%d", argIndex))
-		args = append(args, *params.MinPrice)
-		argIndex++
-	} else if params.MaxPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("Ad.price <= $%d", argIndex))
-		args = append(args, *params.MaxPrice)
-		argIndex++
+	return count, nil
+}
+
+// GetFacets counts ads per animal, breed, locality, region and price bucket. Each facet ignores its own filter,
+// so the sidebar keeps showing the alternatives to the selected value.
+func (repo *AdPostgres) GetFacets(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra, priceBounds []int) (ad.Facets, error) {
+	result := ad.Facets{}
+
+	total, err := repo.CountAds(ctx, params, extra)
+	if err != nil {
+		return result, err
 	}
+	result.Total = total
 
-	if params.Radius != nil {
-		conditions = append(conditions, fmt.Sprintf("(Locality.latitude IS NULL OR Locality.longitude IS NULL OR haversine_distance(Locality.latitude, Locality.longitude, $%d, $%d) <= $%d)", argIndex, argIndex+1, argIndex+2))
-		args = append(args, extra.Latitude, extra.Longitude, *params.Radius)
-		argIndex += 3
+	animalParams := params
+	animalParams.AnimalID = nil
+	animalParams.BreedID = nil
+	if result.Animals, err = repo.getFacetValues(ctx, animalParams, extra, "Animal.id", "Animal.name", ""); err != nil {
+		return result, errors.Wrap(err, "failed to get animal facets")
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	breedParams := params
+	breedParams.BreedID = nil
+	if result.Breeds, err = repo.getFacetValues(ctx, breedParams, extra, "Breed.id", "Breed.name", ""); err != nil {
+		return result, errors.Wrap(err, "failed to get breed facets")
 	}
 
-	if extra.Best == nil {
-		query += " ORDER BY Ad.updated_at DESC "
+	if result.Localities, err = repo.getFacetValues(ctx, params, extra, "Locality.id", "Locality.name", ""); err != nil {
+		return result, errors.Wrap(err, "failed to get locality facets")
+	}
+
+	regionParams := params
+	regionParams.RegionID = nil
+	if result.Regions, err = repo.getFacetValues(ctx, regionParams, extra, "Region.id", "Region.name", "JOIN Region ON Locality.region_id = Region.id"); err != nil {
+		return result, errors.Wrap(err, "failed to get region facets")
+	}
+
+	priceParams := params
+	priceParams.MinPrice = nil
+	priceParams.MaxPrice = nil
+	if result.Prices, err = repo.getPriceBuckets(ctx, priceParams, extra, priceBounds); err != nil {
+		return result, errors.Wrap(err, "failed to get price facets")
+	}
+
+	return result, nil
+}
+
+// GetMapClusters groups the ads inside the area into grid cells of cellSize degrees, or by locality when cellSize
+// is 0. Ads without a locality can not be grouped by it and form clusters of their own.
+func (repo *AdPostgres) GetMapClusters(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra, area ad.MapArea, cellSize float64, sampleSize int, limit int) ([]ad.MapCluster, error) {
+	q := newSearchQuery(params, extra)
+	q.conditions = append(q.conditions, fmt.Sprintf(adInBoundingBox, q.argIndex, q.argIndex+1, q.argIndex+2, q.argIndex+3))
+	q.args = append(q.args, area.MinLatitude, area.MaxLatitude, area.MinLongitude, area.MaxLongitude)
+	q.argIndex += 4
+
+	var groupID, groupBy string
+	if cellSize > 0 {
+		groupID = "NULL::uuid"
+		groupBy = fmt.Sprintf("floor(%s / $%d), floor(%s / $%d)", adLatitude, q.argIndex, adLongitude, q.argIndex)
+		q.args = append(q.args, cellSize)
+		q.argIndex++
 	} else {
-		scoreParts := make([]string, 0)
-
-		if extra.Best.AnimalID == nil {
-			scoreParts = append(scoreParts, "1")
-		} else {
-			scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.animal_id = $%d THEN 1 ELSE 0 END", argIndex))
-			args = append(args, *extra.Best.AnimalID)
-			argIndex++
-		}
+		groupID = "Locality.id"
+		groupBy = "Locality.id, CASE WHEN Locality.id IS NULL THEN Ad.id END"
+	}
 
-		if extra.Best.BreedID == nil {
-			scoreParts = append(scoreParts, "1")
-		} else {
-			scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.breed_id = $%d THEN 1 ELSE 0 END", argIndex))
-			args = append(args, *extra.Best.BreedID)
-			argIndex++
-		}
+	query := fmt.Sprintf(`
+SELECT %s, COUNT(*), AVG(%s), AVG(%s), (array_agg(Ad.id::text ORDER BY Ad.created_at DESC, Ad.id))[1:$%d]
+%s %s
+GROUP BY %s
+ORDER BY COUNT(*) DESC
+LIMIT $%d;`, groupID, adLatitude, adLongitude, q.argIndex, fromAd, q.where(), groupBy, q.argIndex+1)
+	q.args = append(q.args, sampleSize, limit)
+
+	result := make([]ad.MapCluster, 0)
 
-		if extra.Best.MinPrice == nil {
-			scoreParts = append(scoreParts, "1")
-		} else {
-			scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.price >= $%d THEN 1 ELSE 0 END", argIndex))
-			args = append(args, *extra.Best.MinPrice)
-			argIndex++
+	rows, err := repo.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get map clusters from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row        ad.MapCluster
+			localityID []byte
+			adIDs      []string
+		)
+		if err = rows.Scan(&localityID, &row.Count, &row.Latitude, &row.Longitude, &adIDs); err != nil {
+			return result, errors.Wrap(err, "failed to parse map cluster")
 		}
 
-		if extra.Best.MaxPrice == nil {
-			scoreParts = append(scoreParts, "1")
-		} else {
-			scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.price <= $%d THEN 1 ELSE 0 END", argIndex))
-			args = append(args, *extra.Best.MaxPrice)
-			argIndex++
+		if id := uuid.FromBytesOrNil(localityID); id != uuid.Nil {
+			row.LocalityID = &id
 		}
 
-		if extra.Best.Radius == nil {
-			scoreParts = append(scoreParts, "1")
-		} else {
-			scoreParts = append(scoreParts, fmt.Sprintf(`
-			CASE
-				WHEN Locality.latitude IS NULL OR Locality.longitude IS NULL THEN 0
-				WHEN haversine_distance(Locality.latitude, Locality.longitude, $%d, $%d) <= $%d THEN 1
-				ELSE 0
-			END`, argIndex, argIndex+1, argIndex+2))
-			args = append(args, extra.Latitude, extra.Longitude, *extra.Best.Radius)
-			argIndex += 3
+		row.AdIDs = make([]uuid.UUID, 0, len(adIDs))
+		for _, adID := range adIDs {
+			id, err := uuid.FromString(adID)
+			if err != nil {
+				return result, errors.Wrap(err, "failed to parse map cluster ad id")
+			}
+			row.AdIDs = append(row.AdIDs, id)
 		}
 
-		scoreExpr := "(" + strings.Join(scoreParts, " + ") + ")"
-		query += fmt.Sprintf(" ORDER BY %s DESC, Ad.updated_at DESC ", scoreExpr)
+		result = append(result, row)
 	}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d;", argIndex, argIndex+1)
-	args = append(args, params.Limit, params.Offset)
+	return result, nil
+}
 
-	fmt.Printf("%s\n", query)
+func (repo *AdPostgres) getFacetValues(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra, idColumn string, nameColumn string, join string) ([]ad.FacetValue, error) {
+	q := newSearchQuery(params, extra)
+	q.conditions = append(q.conditions, idColumn+" IS NOT NULL")
+	query := fmt.Sprintf("SELECT %s, %s, COUNT(*) %s %s %s GROUP BY %s, %s ORDER BY COUNT(*) DESC, %s;",
+		idColumn, nameColumn, fromAd, join, q.where(), idColumn, nameColumn, nameColumn)
 
-	result := make([]ad.RespAd, 0)
+	result := make([]ad.FacetValue, 0)
+
+	rows, err := repo.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get facets from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row ad.FacetValue
+		if err = rows.Scan(&row.ID, &row.Name, &row.Count); err != nil {
+			return result, errors.Wrap(err, "failed to parse facet")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// getPriceBuckets splits prices by the sorted bounds, bucket i holds prices in [bounds[i-1], bounds[i]).
+func (repo *AdPostgres) getPriceBuckets(ctx context.Context, params ad.SearchParams, extra ad.SearchExtra, bounds []int) ([]ad.PriceBucket, error) {
+	result := make([]ad.PriceBucket, len(bounds)+1)
+	for i := range result {
+		if i > 0 {
+			result[i].Min = &bounds[i-1]
+		}
+		if i < len(bounds) {
+			result[i].Max = &bounds[i]
+		}
+	}
+
+	q := newSearchQuery(params, extra)
+	query := fmt.Sprintf("SELECT width_bucket(Ad.price, $%d::int[]) AS bucket, COUNT(*) %s %s GROUP BY bucket;", q.argIndex, fromAd, q.where())
+	args := append(q.args, bounds)
 
 	rows, err := repo.db.Query(ctx, query, args...)
 	if err != nil {
-		return result, errors.Wrap(err, "failed to get ads from postgres")
+		return result, errors.Wrap(err, "failed to get price buckets from postgres")
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var (
-			row      ad.Ad
-			rowExtra ad.AdInfo
-			lat      *float64
-			lon      *float64
-		)
-		if err = rows.Scan(&row.ID, &row.OwnerID, &row.Status, &row.PhotoURL, &row.Title, &row.Description, &row.Price, &row.AnimalID, &row.BreedID, &row.Contacts, &row.CreatedAt, &row.UpdatedAt, &rowExtra.Username, &rowExtra.AnimalName, &rowExtra.BreedName, &rowExtra.LocalityName, &lat, &lon); err != nil {
-			return result, errors.Wrap(err, "failed to parse ad")
+		var bucket, count int
+		if err = rows.Scan(&bucket, &count); err != nil {
+			return result, errors.Wrap(err, "failed to parse price bucket")
+		}
+		if bucket >= 0 && bucket < len(result) {
+			result[bucket].Count = count
 		}
-		result = append(result, ad.RespAd{Info: row, ExtraInfo: rowExtra})
 	}
 
 	return result, nil
@@ -232,20 +459,59 @@ func (repo *AdPostgres) GetHistory(ctx context.Context, userID uuid.UUID) (*ad.H
 }
 
 func (repo *AdPostgres) GetAd(ctx context.Context, id uuid.UUID) (ad.RespAd, error) {
-	result := ad.Ad{}
-	resultExtra := ad.AdInfo{}
-	if err := repo.db.QueryRow(ctx, getAd, id).Scan(&result.ID, &result.OwnerID, &result.Status, &result.PhotoURL, &result.Title, &result.Description, &result.Price, &result.AnimalID, &result.BreedID, &result.Contacts, &result.CreatedAt, &result.UpdatedAt, &resultExtra.Username, &resultExtra.AnimalName, &resultExtra.BreedName, &resultExtra.LocalityName); err != nil {
+	result, err := scanAd(repo.db.QueryRow(ctx, getAd, utils.GetUserIDFromContext(ctx), id))
+	if err != nil {
 		if goerrors.Is(err, pgx.ErrNoRows) {
 			return ad.RespAd{}, ad.ErrAdNotFound
 		}
 		return ad.RespAd{}, errors.Wrap(err, "failed to get ad from postgres")
 	}
 
-	return ad.RespAd{Info: result, ExtraInfo: resultExtra}, nil
+	return result, nil
+}
+
+func (repo *AdPostgres) GetAdsByIDs(ctx context.Context, ids []uuid.UUID) ([]ad.RespAd, error) {
+	result := make([]ad.RespAd, 0, len(ids))
+
+	idStrings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrings = append(idStrings, id.String())
+	}
+
+	rows, err := repo.db.Query(ctx, getAdsByIDs, utils.GetUserIDFromContext(ctx), idStrings)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get ads by ids from postgres")
+	}
+	defer rows.Close()
+
+	found := make(map[uuid.UUID]ad.RespAd, len(ids))
+	for rows.Next() {
+		row, err := scanAd(rows)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse ad")
+		}
+		found[row.Info.ID] = row
+	}
+
+	for _, id := range ids {
+		if row, ok := found[id]; ok {
+			result = append(result, row)
+		}
+	}
+
+	return result, nil
 }
 
-func (repo *AdPostgres) CreateAd(ctx context.Context, adData ad.Ad) error {
-	if _, err := repo.db.Exec(ctx, createAd, adData.ID, adData.OwnerID, adData.Status, adData.PhotoURL, adData.Title, adData.Description, adData.Price, adData.AnimalID, adData.BreedID, adData.Contacts, adData.CreatedAt, adData.UpdatedAt); err != nil {
+func (repo *AdPostgres) CreateAd(ctx context.Context, adData ad.Ad, photos []ad.Photo) error {
+	var publishedAt *time.Time
+	if adData.Status == ad.Actual {
+		publishedAt = &adData.CreatedAt
+	}
+
+	args := []interface{}{adData.ID, adData.OwnerID, adData.Status, adData.PhotoURL, adData.Title, adData.Description, adData.Price, adData.AnimalID, adData.BreedID, adData.Contacts, adData.LocalityID, adData.Latitude, adData.Longitude, adData.CreatedAt, adData.UpdatedAt, publishedAt}
+	query := fmt.Sprintf(createAd, photoRowsArgs(len(args)+1)...)
+
+	if _, err := repo.db.Exec(ctx, query, append(args, photoArrays(photos)...)...); err != nil {
 		if strings.Contains(err.Error(), "violates foreign key constraint") {
 			return ad.ErrInvalidForeignKey
 		}
@@ -303,10 +569,16 @@ func (repo *AdPostgres) UpdateAd(ctx context.Context, id uuid.UUID, form ad.Upda
 		argIndex++
 	}
 
+	if form.Location != nil {
+		conditions = append(conditions, fmt.Sprintf("locality_id=$%d, latitude=$%d, longitude=$%d", argIndex, argIndex+1, argIndex+2))
+		args = append(args, form.Location.LocalityID, form.Location.Latitude, form.Location.Longitude)
+		argIndex += 3
+	}
+
 	if form.Status != nil {
-		conditions = append(conditions, fmt.Sprintf("status=$%d", argIndex))
-		args = append(args, *form.Status)
-		argIndex++
+		conditions = append(conditions, fmt.Sprintf("status=$%[1]d, published_at=CASE WHEN status <> 'A' AND $%[1]d = 'A' THEN $%[2]d ELSE published_at END", argIndex, argIndex+1))
+		args = append(args, *form.Status, now)
+		argIndex += 2
 	}
 
 	conditions = append(conditions, fmt.Sprintf("updated_at=$%d", argIndex))
@@ -338,3 +610,453 @@ func (repo *AdPostgres) DeleteAd(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+// AddPhotos inserts the whole batch or nothing. ErrPhotosChanged means another request took the positions first.
+func (repo *AdPostgres) AddPhotos(ctx context.Context, adID uuid.UUID, photos []ad.Photo) error {
+	query := fmt.Sprintf(addPhotos, photoRowsArgs(2)...)
+
+	if _, err := repo.db.Exec(ctx, query, append([]interface{}{adID}, photoArrays(photos)...)...); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		if strings.Contains(err.Error(), "violates unique constraint") {
+			return ad.ErrPhotosChanged
+		}
+		return errors.Wrap(err, "failed to add photos to postgres")
+	}
+
+	return nil
+}
+
+// ReplaceCoverPhoto removes the old cover, uuid.Nil if there is none, and makes the photo the cover in one statement.
+func (repo *AdPostgres) ReplaceCoverPhoto(ctx context.Context, oldCoverID uuid.UUID, photo ad.Photo) error {
+	if _, err := repo.db.Exec(ctx, replaceCoverPhoto, oldCoverID, photo.AdID, photo.ID, photo.URL, photo.MediumURL, photo.ThumbnailURL, photo.Position, photo.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		if strings.Contains(err.Error(), "violates unique constraint") {
+			return ad.ErrPhotosChanged
+		}
+		return errors.Wrap(err, "failed to replace cover photo in postgres")
+	}
+
+	return nil
+}
+
+// RemovePhoto removes the photo and makes newCoverID the cover, uuid.Nil keeps the current one.
+func (repo *AdPostgres) RemovePhoto(ctx context.Context, adID uuid.UUID, photoID uuid.UUID, newCoverID uuid.UUID, now time.Time) error {
+	tag, err := repo.db.Exec(ctx, removePhoto, adID, photoID, newCoverID, now)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove photo from postgres")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ad.ErrPhotoNotFound
+	}
+
+	return nil
+}
+
+func (repo *AdPostgres) ReorderPhotos(ctx context.Context, adID uuid.UUID, photoIDs []uuid.UUID) error {
+	idStrings := make([]string, 0, len(photoIDs))
+	for _, id := range photoIDs {
+		idStrings = append(idStrings, id.String())
+	}
+
+	if _, err := repo.db.Exec(ctx, reorderPhotos, adID, idStrings); err != nil {
+		return errors.Wrap(err, "failed to reorder photos in postgres")
+	}
+
+	return nil
+}
+
+func (repo *AdPostgres) SetCoverPhoto(ctx context.Context, adID uuid.UUID, photoID uuid.UUID, now time.Time) error {
+	if _, err := repo.db.Exec(ctx, setCoverPhoto, adID, photoID, now); err != nil {
+		return errors.Wrap(err, "failed to set cover photo in postgres")
+	}
+
+	return nil
+}
+
+// photoRowsArgs numbers the seven photoRows placeholders starting from first.
+func photoRowsArgs(first int) []interface{} {
+	result := make([]interface{}, 0, 7)
+	for i := 0; i < 7; i++ {
+		result = append(result, first+i)
+	}
+	return result
+}
+
+// photoArrays splits the photos into the column arrays unnested by photoRows.
+func photoArrays(photos []ad.Photo) []interface{} {
+	var (
+		ids           = make([]string, 0, len(photos))
+		urls          = make([]string, 0, len(photos))
+		mediumURLs    = make([]string, 0, len(photos))
+		thumbnailURLs = make([]string, 0, len(photos))
+		positions     = make([]int, 0, len(photos))
+		covers        = make([]bool, 0, len(photos))
+		createdAt     = make([]time.Time, 0, len(photos))
+	)
+
+	for _, photo := range photos {
+		ids = append(ids, photo.ID.String())
+		urls = append(urls, photo.URL)
+		mediumURLs = append(mediumURLs, photo.MediumURL)
+		thumbnailURLs = append(thumbnailURLs, photo.ThumbnailURL)
+		positions = append(positions, photo.Position)
+		covers = append(covers, photo.IsCover)
+		createdAt = append(createdAt, photo.CreatedAt)
+	}
+
+	return []interface{}{ids, urls, mediumURLs, thumbnailURLs, positions, covers, createdAt}
+}
+
+// scanAd reads the selectAdFields columns followed by any extra columns into the extra destinations.
+func scanAd(row pgx.Row, extra ...interface{}) (ad.RespAd, error) {
+	var (
+		result      ad.Ad
+		resultExtra ad.AdInfo
+		localityID  []byte
+		anonViews   int
+		photos      []byte
+		resp        ad.RespAd
+	)
+
+	dest := []interface{}{
+		&result.ID, &result.OwnerID, &result.Status, &result.ModerationReason,
+		&result.PhotoURL, &result.Title, &result.Description, &result.Price, &result.AnimalID, &result.BreedID, &result.Contacts,
+		&localityID, &result.Latitude, &result.Longitude,
+		&result.CreatedAt, &result.UpdatedAt,
+		&resultExtra.Username, &resultExtra.AnimalName, &resultExtra.BreedName, &resultExtra.LocalityName,
+		&resultExtra.Latitude, &resultExtra.Longitude,
+		&resp.FavoritesCount, &resp.IsFavorite,
+		&resultExtra.UniqueViews, &anonViews,
+		&photos,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if err := json.Unmarshal(photos, &resp.Photos); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to parse photos")
+	}
+	resultExtra.Views = resultExtra.UniqueViews + anonViews
+
+	if id := uuid.FromBytesOrNil(localityID); id != uuid.Nil {
+		result.LocalityID = &id
+	}
+
+	resp.Info = result
+	resp.ExtraInfo = resultExtra
+	return resp, nil
+}
+
+// searchQuery collects the WHERE conditions shared by SearchAds and CountAds, argIndex is the next free placeholder.
+// The centre placeholders are reserved by the first distance call, a query not using the distance does not send them.
+type searchQuery struct {
+	conditions     []string
+	args           []interface{}
+	argIndex       int
+	textArgIndex   int
+	hasCenter      bool
+	center         [2]float64
+	centerArgIndex int
+}
+
+func newSearchQuery(params ad.SearchParams, extra ad.SearchExtra, args ...interface{}) *searchQuery {
+	q := &searchQuery{
+		args:     args,
+		argIndex: len(args) + 1,
+	}
+
+	if params.Query != nil {
+		q.textArgIndex = q.argIndex
+		q.conditions = append(q.conditions, adDocument+" @@ "+fmt.Sprintf(textQuery, q.textArgIndex))
+		q.args = append(q.args, *params.Query)
+		q.argIndex++
+	}
+
+	if extra.HasLocation {
+		q.hasCenter = true
+		q.center = [2]float64{extra.Latitude, extra.Longitude}
+	}
+
+	if len(params.Statuses) > 0 {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.status::text = ANY($%d::text[])", q.argIndex))
+		q.args = append(q.args, params.Statuses)
+		q.argIndex++
+	} else if !params.AllStatuses {
+		q.conditions = append(q.conditions, "Ad.status = 'A'")
+	} else if !params.IncludeModerated {
+		q.conditions = append(q.conditions, "Ad.status NOT IN "+moderatedStatuses)
+	}
+
+	if params.OwnerID != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.owner_id=$%d", q.argIndex))
+		q.args = append(q.args, *params.OwnerID)
+		q.argIndex++
+	}
+
+	if params.AnimalID != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.animal_id=$%d", q.argIndex))
+		q.args = append(q.args, *params.AnimalID)
+		q.argIndex++
+	}
+
+	if params.BreedID != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.breed_id=$%d", q.argIndex))
+		q.args = append(q.args, *params.BreedID)
+		q.argIndex++
+	}
+
+	if params.MinPrice != nil && params.MaxPrice != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.price BETWEEN $%d AND $%d", q.argIndex, q.argIndex+1))
+		q.args = append(q.args, *params.MinPrice, *params.MaxPrice)
+		q.argIndex += 2
+	} else if params.MinPrice != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.price >= $%d", q.argIndex))
+		q.args = append(q.args, *params.MinPrice)
+		q.argIndex++
+	} else if params.MaxPrice != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.price <= $%d", q.argIndex))
+		q.args = append(q.args, *params.MaxPrice)
+		q.argIndex++
+	}
+
+	if params.Radius != nil && q.hasCenter {
+		minLat, maxLat, minLon, maxLon := utils.BoundingBox(extra.Latitude, extra.Longitude, float64(*params.Radius))
+		bbox := fmt.Sprintf(adInBoundingBox, q.argIndex, q.argIndex+1, q.argIndex+2, q.argIndex+3)
+		q.args = append(q.args, minLat, maxLat, minLon, maxLon)
+		q.argIndex += 4
+
+		// The bounding box narrows the ads down by the indexes, the distance cuts off its corners.
+		distance := q.distance()
+		q.conditions = append(q.conditions, fmt.Sprintf("%s AND %s <= $%d", bbox, distance, q.argIndex))
+		q.args = append(q.args, *params.Radius)
+		q.argIndex++
+	}
+
+	if params.RegionID != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Locality.region_id=$%d", q.argIndex))
+		q.args = append(q.args, *params.RegionID)
+		q.argIndex++
+	}
+
+	if params.PublishedAfter != nil {
+		q.conditions = append(q.conditions, fmt.Sprintf("Ad.published_at > $%d", q.argIndex))
+		q.args = append(q.args, *params.PublishedAfter)
+		q.argIndex++
+	}
+
+	return q
+}
+
+// distance returns the distance expression to the search centre, it must only be used when hasCenter is set.
+func (q *searchQuery) distance() string {
+	if q.centerArgIndex == 0 {
+		q.centerArgIndex = q.argIndex
+		q.args = append(q.args, q.center[0], q.center[1])
+		q.argIndex += 2
+	}
+
+	return fmt.Sprintf(adDistance, q.centerArgIndex, q.centerArgIndex+1)
+}
+
+func (q *searchQuery) where() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// searchOrder sorts by key, then by timeColumn, then by Ad.id, all in the same direction so a single row
+// comparison selects the ads after a cursor. key and timeColumn are empty when not used by the order.
+// A nullable key puts the rows without it last, their cursors carry a nil key.
+type searchOrder struct {
+	key        string
+	nullable   bool
+	timeColumn string
+	desc       bool
+}
+
+func (q *searchQuery) order(params ad.SearchParams, extra ad.SearchExtra) searchOrder {
+	switch params.Sort {
+	case ad.SortNewest:
+		return searchOrder{timeColumn: "Ad.created_at", desc: true}
+	case ad.SortOldest:
+		return searchOrder{timeColumn: "Ad.created_at", desc: false}
+	case ad.SortPriceAsc:
+		return searchOrder{key: "Ad.price", desc: false}
+	case ad.SortPriceDesc:
+		return searchOrder{key: "Ad.price", desc: true}
+	case ad.SortDistance:
+		if !q.hasCenter {
+			return searchOrder{key: "NULL", nullable: true, desc: false}
+		}
+		// Ads without a location have no distance and go last.
+		return searchOrder{key: q.distance(), nullable: true, desc: false}
+	default:
+		return searchOrder{key: q.scoreExpr(extra), timeColumn: "Ad.updated_at", desc: true}
+	}
+}
+
+func (o searchOrder) columns() []string {
+	columns := make([]string, 0, 3)
+	if o.key != "" {
+		columns = append(columns, o.key+"::float8")
+	}
+
+	return append(columns, o.tieColumns()...)
+}
+
+// tieColumns are the columns after the key, they are never NULL.
+func (o searchOrder) tieColumns() []string {
+	columns := make([]string, 0, 2)
+	if o.timeColumn != "" {
+		columns = append(columns, o.timeColumn)
+	}
+
+	return append(columns, "Ad.id")
+}
+
+func (o searchOrder) orderBy() string {
+	direction := " ASC"
+	if o.desc {
+		direction = " DESC"
+	}
+
+	columns := o.columns()
+	for i := range columns {
+		columns[i] += direction
+	}
+	if o.nullable {
+		columns[0] += " NULLS LAST"
+	}
+
+	return " ORDER BY " + strings.Join(columns, ", ") + " "
+}
+
+// validCursorKey tells whether a cursor with the key may continue this order.
+func (o searchOrder) validCursorKey(key *float64) bool {
+	if o.key == "" {
+		return key == nil
+	}
+
+	return key != nil || o.nullable
+}
+
+func (q *searchQuery) addCursorCondition(order searchOrder, cursor ad.SearchCursor) {
+	placeholders := make([]string, 0, 3)
+
+	keyPlaceholder := ""
+	if order.key != "" && cursor.Key != nil {
+		keyPlaceholder = fmt.Sprintf("$%d", q.argIndex)
+		q.args = append(q.args, *cursor.Key)
+		q.argIndex++
+	}
+
+	switch order.timeColumn {
+	case "Ad.created_at":
+		placeholders = append(placeholders, fmt.Sprintf("$%d", q.argIndex))
+		q.args = append(q.args, cursor.CreatedAt)
+		q.argIndex++
+	case "Ad.updated_at":
+		placeholders = append(placeholders, fmt.Sprintf("$%d", q.argIndex))
+		q.args = append(q.args, cursor.UpdatedAt)
+		q.argIndex++
+	}
+
+	placeholders = append(placeholders, fmt.Sprintf("$%d", q.argIndex))
+	q.args = append(q.args, cursor.ID)
+	q.argIndex++
+
+	operator := ">"
+	if order.desc {
+		operator = "<"
+	}
+
+	tie := fmt.Sprintf("(%s) %s (%s)", strings.Join(order.tieColumns(), ", "), operator, strings.Join(placeholders, ", "))
+
+	switch {
+	case order.key == "":
+		q.conditions = append(q.conditions, tie)
+	case keyPlaceholder == "":
+		// The cursor is among the rows without a key, only the rest of them follow it.
+		q.conditions = append(q.conditions, fmt.Sprintf("(%s::float8 IS NULL AND %s)", order.key, tie))
+	default:
+		after := fmt.Sprintf("(%s) %s (%s)", strings.Join(order.columns(), ", "), operator, keyPlaceholder+", "+strings.Join(placeholders, ", "))
+		if order.nullable {
+			after = fmt.Sprintf("(%s::float8 IS NULL OR %s)", order.key, after)
+		}
+		q.conditions = append(q.conditions, after)
+	}
+}
+
+// scoreExpr returns the ranking expression built from the history and the text query, or "" when neither is set.
+func (q *searchQuery) scoreExpr(extra ad.SearchExtra) string {
+	if extra.Best == nil && q.textArgIndex == 0 {
+		return ""
+	}
+
+	if extra.Best == nil {
+		return fmt.Sprintf(textRank, q.textArgIndex)
+	}
+
+	scoreParts := make([]string, 0)
+
+	if extra.Best.AnimalID == nil {
+		scoreParts = append(scoreParts, "1")
+	} else {
+		scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.animal_id = $%d THEN 1 ELSE 0 END", q.argIndex))
+		q.args = append(q.args, *extra.Best.AnimalID)
+		q.argIndex++
+	}
+
+	if extra.Best.BreedID == nil {
+		scoreParts = append(scoreParts, "1")
+	} else {
+		scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.breed_id = $%d THEN 1 ELSE 0 END", q.argIndex))
+		q.args = append(q.args, *extra.Best.BreedID)
+		q.argIndex++
+	}
+
+	if extra.Best.MinPrice == nil {
+		scoreParts = append(scoreParts, "1")
+	} else {
+		scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.price >= $%d THEN 1 ELSE 0 END", q.argIndex))
+		q.args = append(q.args, *extra.Best.MinPrice)
+		q.argIndex++
+	}
+
+	if extra.Best.MaxPrice == nil {
+		scoreParts = append(scoreParts, "1")
+	} else {
+		scoreParts = append(scoreParts, fmt.Sprintf("CASE WHEN Ad.price <= $%d THEN 1 ELSE 0 END", q.argIndex))
+		q.args = append(q.args, *extra.Best.MaxPrice)
+		q.argIndex++
+	}
+
+	if extra.Best.Radius == nil || !q.hasCenter {
+		scoreParts = append(scoreParts, "1")
+	} else {
+		distance := q.distance()
+		scoreParts = append(scoreParts, fmt.Sprintf(`
+			CASE
+				WHEN `+adLatitude+` IS NULL THEN 0
+				WHEN %s <= $%d THEN 1
+				ELSE 0
+			END`, distance, q.argIndex))
+		q.args = append(q.args, *extra.Best.Radius)
+		q.argIndex++
+	}
+
+	scoreExpr := "(" + strings.Join(scoreParts, " + ") + ")"
+	if q.textArgIndex != 0 {
+		scoreExpr = fmt.Sprintf("(%s + %d * %s)", scoreExpr, textRankWeight, fmt.Sprintf(textRank, q.textArgIndex))
+	}
+
+	return scoreExpr
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"context"
+	goerrors "errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+var errRecorded = goerrors.New("query recorded")
+
+type recordedQuery struct {
+	sql  string
+	args []interface{}
+}
+
+// recordingDB fails every query after recording it, the tests look at the SQL sent to postgres.
+type recordingDB struct {
+	queries []recordedQuery
+}
+
+func (db *recordingDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.queries = append(db.queries, recordedQuery{sql: sql, args: args})
+	return nil, errRecorded
+}
+
+func (db *recordingDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.queries = append(db.queries, recordedQuery{sql: sql, args: args})
+	return nil, errRecorded
+}
+
+func (db *recordingDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	db.queries = append(db.queries, recordedQuery{sql: sql, args: args})
+	return errorRow{}
+}
+
+type errorRow struct{}
+
+func (errorRow) Scan(...interface{}) error {
+	return errRecorded
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// placeholders returns the set of $N used by the query, postgres expects an argument for each one and no other.
+func placeholders(sql string) map[int]bool {
+	result := make(map[int]bool)
+	for _, match := range placeholder.FindAllStringSubmatch(sql, -1) {
+		n, _ := strconv.Atoi(match[1])
+		result[n] = true
+	}
+
+	return result
+}
+
+func TestSearchQueriesSendOnlyUsedArgs(t *testing.T) {
+	price := 1000
+	radius := 10
+	tests := []struct {
+		name   string
+		params ad.SearchParams
+	}{
+		{name: "distance sort without radius", params: ad.SearchParams{Sort: ad.SortDistance}},
+		{name: "distance sort with filters", params: ad.SearchParams{Sort: ad.SortDistance, MaxPrice: &price}},
+		{name: "distance sort with radius", params: ad.SearchParams{Sort: ad.SortDistance, Radius: &radius}},
+	}
+
+	extra := ad.SearchExtra{HasLocation: true, Latitude: 55.75, Longitude: 37.62}
+	area := ad.MapArea{MinLatitude: 55, MaxLatitude: 56, MinLongitude: 37, MaxLongitude: 38}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &recordingDB{}
+			repo := NewAdPostgres(db)
+
+			_, _ = repo.CountAds(context.Background(), tt.params, extra)
+			_, _ = repo.getFacetValues(context.Background(), tt.params, extra, "Animal.id", "Animal.name", "")
+			_, _ = repo.getPriceBuckets(context.Background(), tt.params, extra, []int{100, 1000})
+			_, _ = repo.GetMapClusters(context.Background(), tt.params, extra, area, 0.5, 3, 10)
+			_, _ = repo.GetMapClusters(context.Background(), tt.params, extra, area, 0, 3, 10)
+
+			if len(db.queries) != 5 {
+				t.Fatalf("queries = %d, want 5", len(db.queries))
+			}
+			for _, query := range db.queries {
+				used := placeholders(query.sql)
+				if len(used) != len(query.args) {
+					t.Errorf("placeholders = %d, args = %d in %s", len(used), len(query.args), query.sql)
+				}
+				for i := 1; i <= len(query.args); i++ {
+					if !used[i] {
+						t.Errorf("argument $%d is not used in %s", i, query.sql)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDistanceCursorCondition(t *testing.T) {
+	distance := 2.5
+	tests := []struct {
+		name     string
+		key      *float64
+		wantArgs int
+	}{
+		{name: "after an ad with a distance", key: &distance, wantArgs: 2},
+		{name: "after an ad without a location", key: nil, wantArgs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := ad.SearchParams{Sort: ad.SortDistance}
+			q := newSearchQuery(params, ad.SearchExtra{HasLocation: true, Latitude: 55.75, Longitude: 37.62})
+			order := q.order(params, ad.SearchExtra{})
+
+			if !order.validCursorKey(tt.key) {
+				t.Fatal("cursor key rejected")
+			}
+			if orderBy := order.orderBy(); !strings.Contains(orderBy, "ASC NULLS LAST, Ad.id ASC") {
+				t.Errorf("orderBy = %s, want the distance NULLS LAST", orderBy)
+			}
+
+			argsBefore := len(q.args)
+			q.addCursorCondition(order, ad.SearchCursor{Sort: ad.SortDistance, Key: tt.key, ID: uuid.NewV4()})
+
+			if got := len(q.args) - argsBefore; got != tt.wantArgs {
+				t.Errorf("cursor args = %d, want %d", got, tt.wantArgs)
+			}
+
+			condition := q.conditions[len(q.conditions)-1]
+			if tt.key == nil && !strings.Contains(condition, "::float8 IS NULL AND (Ad.id) >") {
+				t.Errorf("condition = %s, want only the ads without a location after the cursor", condition)
+			}
+			if tt.key != nil && !strings.Contains(condition, "::float8 IS NULL OR (") {
+				t.Errorf("condition = %s, want the ads without a location after the cursor too", condition)
+			}
+		})
+	}
+}
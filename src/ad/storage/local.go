@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	goerrors "errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/utils"
+)
+
+type LocalPhotoStorage struct {
+	basePath string
+}
+
+func NewLocalPhotoStorage(basePath string) *LocalPhotoStorage {
+	return &LocalPhotoStorage{basePath: basePath}
+}
+
+func (s *LocalPhotoStorage) SavePhoto(ctx context.Context, name string, data io.ReadSeeker) error {
+	if err := utils.WriteFileOnDisk(path.Join(s.basePath, name), "", data); err != nil {
+		return errors.Wrap(err, "failed to write photo on disk")
+	}
+
+	return nil
+}
+
+func (s *LocalPhotoStorage) GetPhoto(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(path.Join(s.basePath, name))
+	if err != nil {
+		if goerrors.Is(err, fs.ErrNotExist) {
+			return nil, ad.ErrPhotoNotFound
+		}
+		return nil, errors.Wrap(err, "failed to read photo from disk")
+	}
+
+	return data, nil
+}
+
+func (s *LocalPhotoStorage) RemovePhoto(ctx context.Context, name string) error {
+	if err := os.Remove(path.Join(s.basePath, name)); err != nil {
+		if goerrors.Is(err, fs.ErrNotExist) {
+			return ad.ErrPhotoNotFound
+		}
+		return errors.Wrap(err, "failed to remove photo from disk")
+	}
+
+	return nil
+}
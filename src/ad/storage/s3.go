@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+)
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3AmzDateFormat = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+	s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+)
+
+// S3PhotoStorage keeps photos in an S3-compatible bucket (AWS S3, MinIO, etc.).
+// Requests are signed with AWS Signature Version 4.
+type S3PhotoStorage struct {
+	client    *http.Client
+	cfg       config.S3Config
+	accessKey string
+	secretKey string
+}
+
+func NewS3PhotoStorage(cfg config.S3Config, accessKey string, secretKey string) *S3PhotoStorage {
+	return &S3PhotoStorage{
+		client:    &http.Client{Timeout: cfg.Timeout},
+		cfg:       cfg,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+}
+
+func (s *S3PhotoStorage) SavePhoto(ctx context.Context, name string, data io.ReadSeeker) error {
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to set zero seek offset on photo")
+	}
+
+	body, err := io.ReadAll(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to read photo")
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if _, err = s.do(req); err != nil {
+		return errors.Wrap(err, "failed to put photo to s3")
+	}
+
+	return nil
+}
+
+func (s *S3PhotoStorage) GetPhoto(ctx context.Context, name string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get photo from s3")
+	}
+
+	return data, nil
+}
+
+func (s *S3PhotoStorage) RemovePhoto(ctx context.Context, name string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.do(req); err != nil {
+		return errors.Wrap(err, "failed to remove photo from s3")
+	}
+
+	return nil
+}
+
+func (s *S3PhotoStorage) newRequest(ctx context.Context, method string, name string, body []byte) (*http.Request, error) {
+	endpoint, err := url.Parse(s.cfg.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse s3 endpoint")
+	}
+
+	objectURL := *endpoint
+	if s.cfg.UsePathStyle {
+		objectURL.Path = path.Join("/", endpoint.Path, s.cfg.Bucket, name)
+	} else {
+		objectURL.Host = s.cfg.Bucket + "." + endpoint.Host
+		objectURL.Path = path.Join("/", endpoint.Path, name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create s3 request")
+	}
+
+	s.sign(req, body, time.Now().UTC())
+	return req, nil
+}
+
+func (s *S3PhotoStorage) do(req *http.Request) ([]byte, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ad.ErrPhotoNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("status code: %d, respBody: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
+
+func (s *S3PhotoStorage) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format(s3AmzDateFormat)
+	date := now.Format(s3DateFormat)
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, payloadHash, amzDate),
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.cfg.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.cfg.Region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, scope, s3SignedHeaders, signature,
+	))
+}
+
+func hashHex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+)
+
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+func NewPhotoStorage(cfg config.StorageConfig) (ad.PhotoStorage, error) {
+	switch cfg.Type {
+	case TypeLocal, "":
+		return NewLocalPhotoStorage(os.Getenv("PHOTO_BASE_PATH")), nil
+	case TypeS3:
+		return NewS3PhotoStorage(cfg.S3, os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY")), nil
+	default:
+		return nil, errors.Errorf("unknown photo storage type: %s", cfg.Type)
+	}
+}
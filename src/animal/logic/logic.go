@@ -5,14 +5,16 @@ import (
 
 	"github.com/satori/uuid"
 	"pet_adopter/src/animal"
+	"pet_adopter/src/audit"
 )
 
 type AnimalLogic struct {
-	repo animal.AnimalRepo
+	repo     animal.AnimalRepo
+	auditLog audit.AuditLogic
 }
 
-func NewAnimalLogic(repo animal.AnimalRepo) AnimalLogic {
-	return AnimalLogic{repo: repo}
+func NewAnimalLogic(repo animal.AnimalRepo, auditLog audit.AuditLogic) AnimalLogic {
+	return AnimalLogic{repo: repo, auditLog: auditLog}
 }
 
 func (logic *AnimalLogic) GetAnimals(ctx context.Context) ([]animal.Animal, error) {
@@ -25,9 +27,24 @@ func (logic *AnimalLogic) GetAnimalByID(ctx context.Context, id uuid.UUID) (anim
 
 func (logic *AnimalLogic) AddAnimal(ctx context.Context, name string) (animal.Animal, error) {
 	result := animal.Animal{ID: uuid.NewV4(), Name: name}
-	return result, logic.repo.AddAnimal(ctx, result)
+	if err := logic.repo.AddAnimal(ctx, result); err != nil {
+		return result, err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionCreate, audit.EntityAnimal, result.ID, nil, result)
+	return result, nil
 }
 
 func (logic *AnimalLogic) RemoveAnimalByID(ctx context.Context, id uuid.UUID) error {
-	return logic.repo.RemoveAnimalByID(ctx, id)
+	before, err := logic.repo.GetAnimalByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = logic.repo.RemoveAnimalByID(ctx, id); err != nil {
+		return err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionDelete, audit.EntityAnimal, id, before, nil)
+	return nil
 }
@@ -0,0 +1,82 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+)
+
+var (
+	ErrApplicationNotFound = errors.New("application not found")
+	ErrAlreadyApplied      = errors.New("already applied")
+	ErrNotOwner            = errors.New("not owner")
+	ErrOwnAd               = errors.New("can not apply to own ad")
+	ErrAdNotActual         = errors.New("ad is not actual")
+	ErrNotPending          = errors.New("application is not pending")
+	ErrInvalidApplication  = errors.New("invalid application")
+)
+
+const (
+	Pending  = "P"
+	Accepted = "A"
+	Rejected = "R"
+
+	AsAdopter = "adopter"
+	AsOwner   = "owner"
+
+	MaxMessageLength = 4096
+	MaxAnswersCount  = 32
+	MaxAnswerLength  = 1024
+
+	// AdoptedByAnotherReply is left on the pending applications rejected automatically when another one is accepted,
+	// their adopters also get a savedsearch.AdoptedByAnother notification.
+	AdoptedByAnotherReply = "The pet has been adopted by another applicant."
+)
+
+type Application struct {
+	ID        uuid.UUID         `json:"id"`
+	AdID      uuid.UUID         `json:"ad_id"`
+	OwnerID   uuid.UUID         `json:"owner_id"`
+	AdopterID uuid.UUID         `json:"adopter_id"`
+	Status    string            `json:"status"`
+	Answers   map[string]string `json:"answers"`
+	Message   string            `json:"message"`
+	Reply     string            `json:"reply"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type ApplicationForm struct {
+	Answers map[string]string `json:"answers"`
+	Message string            `json:"message"`
+}
+
+type ApplicationInfo struct {
+	AdTitle         string `json:"ad_title"`
+	AdopterUsername string `json:"adopter_username"`
+	OwnerUsername   string `json:"owner_username"`
+}
+
+type RespApplication struct {
+	Info      Application     `json:"info"`
+	ExtraInfo ApplicationInfo `json:"extra_info"`
+}
+
+type ApplicationRepo interface {
+	CreateApplication(ctx context.Context, application Application) error
+	GetApplication(ctx context.Context, id uuid.UUID) (RespApplication, error)
+	GetAdApplications(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]RespApplication, error)
+	GetUserApplications(ctx context.Context, userID uuid.UUID, role string, limit int, offset int) ([]RespApplication, error)
+	RejectApplication(ctx context.Context, id uuid.UUID, reply string, now time.Time) error
+	AcceptApplication(ctx context.Context, id uuid.UUID, reply string, now time.Time) ([]Application, error)
+}
+
+type ApplicationLogic interface {
+	Apply(ctx context.Context, adID uuid.UUID, form ApplicationForm) (RespApplication, error)
+	GetAdApplications(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]RespApplication, error)
+	GetHistory(ctx context.Context, role string, limit int, offset int) ([]RespApplication, error)
+	Accept(ctx context.Context, id uuid.UUID, reply string) (RespApplication, error)
+	Reject(ctx context.Context, id uuid.UUID, reply string) (RespApplication, error)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/application"
+	"pet_adopter/src/config"
+	"pet_adopter/src/utils"
+)
+
+type ApplicationHandler struct {
+	logic application.ApplicationLogic
+	cfg   config.AdConfig
+}
+
+func NewApplicationHandler(logic application.ApplicationLogic, cfg config.AdConfig) *ApplicationHandler {
+	return &ApplicationHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type ApplicationResponse struct {
+	Application application.RespApplication `json:"application"`
+}
+
+type ApplicationsResponse struct {
+	Applications []application.RespApplication `json:"applications"`
+}
+
+func (h *ApplicationHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req application.ApplicationForm
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.logic.Apply(ctx, adID, req)
+	if err != nil {
+		handleApplicationError(ctx, w, err)
+		return
+	}
+
+	result := ApplicationResponse{Application: created}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ApplicationHandler) GetAdApplications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	applications, err := h.logic.GetAdApplications(ctx, adID, limit, offset)
+	if err != nil {
+		handleApplicationError(ctx, w, err)
+		return
+	}
+
+	result := ApplicationsResponse{Applications: applications}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ApplicationHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	role := r.URL.Query().Get("role")
+	if role == "" {
+		role = application.AsAdopter
+	}
+
+	if role != application.AsAdopter && role != application.AsOwner {
+		utils.LogErrorMessage(ctx, fmt.Sprintf("invalid role: %s", role))
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	applications, err := h.logic.GetHistory(ctx, role, limit, offset)
+	if err != nil {
+		handleApplicationError(ctx, w, err)
+		return
+	}
+
+	result := ApplicationsResponse{Applications: applications}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type ReviewRequest struct {
+	Reply string `json:"reply"`
+}
+
+func (h *ApplicationHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.logic.Accept)
+}
+
+func (h *ApplicationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.logic.Reject)
+}
+
+func (h *ApplicationHandler) review(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id uuid.UUID, reply string) (application.RespApplication, error)) {
+	ctx := r.Context()
+
+	applicationID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid application id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req ReviewRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	reviewed, err := action(ctx, applicationID, req.Reply)
+	if err != nil {
+		handleApplicationError(ctx, w, err)
+		return
+	}
+
+	result := ApplicationResponse{Application: reviewed}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleApplicationError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, ad.ErrAdNotFound):
+		utils.LogError(ctx, err, "ad not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, application.ErrApplicationNotFound):
+		utils.LogError(ctx, err, "application not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, application.ErrNotOwner):
+		utils.LogError(ctx, err, "access denied")
+		http.Error(w, utils.NotFound, http.StatusForbidden)
+	case goerrors.Is(err, application.ErrAlreadyApplied),
+		goerrors.Is(err, application.ErrOwnAd),
+		goerrors.Is(err, application.ErrAdNotActual),
+		goerrors.Is(err, application.ErrNotPending),
+		goerrors.Is(err, application.ErrInvalidApplication):
+		utils.LogError(ctx, err, "invalid application operation")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	default:
+		utils.LogError(ctx, err, "failed to perform operation")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,193 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/application"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/utils"
+)
+
+type ApplicationLogic struct {
+	repo     application.ApplicationRepo
+	adRepo   ad.AdRepo
+	auditLog audit.AuditLogic
+}
+
+func NewApplicationLogic(repo application.ApplicationRepo, adRepo ad.AdRepo, auditLog audit.AuditLogic) ApplicationLogic {
+	return ApplicationLogic{
+		repo:     repo,
+		adRepo:   adRepo,
+		auditLog: auditLog,
+	}
+}
+
+func (l *ApplicationLogic) Apply(ctx context.Context, adID uuid.UUID, form application.ApplicationForm) (application.RespApplication, error) {
+	now := time.Now().Local()
+	userID := utils.GetUserIDFromContext(ctx)
+
+	currentAd, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	if currentAd.Info.OwnerID == userID {
+		return application.RespApplication{}, application.ErrOwnAd
+	}
+
+	if currentAd.Info.Status != ad.Actual {
+		return application.RespApplication{}, application.ErrAdNotActual
+	}
+
+	form.Message = strings.TrimSpace(form.Message)
+	if err = validateForm(form); err != nil {
+		return application.RespApplication{}, err
+	}
+
+	if form.Answers == nil {
+		form.Answers = make(map[string]string)
+	}
+
+	row := application.Application{
+		ID:        uuid.NewV4(),
+		AdID:      adID,
+		OwnerID:   currentAd.Info.OwnerID,
+		AdopterID: userID,
+		Status:    application.Pending,
+		Answers:   form.Answers,
+		Message:   form.Message,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err = l.repo.CreateApplication(ctx, row); err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to create application")
+	}
+
+	return l.repo.GetApplication(ctx, row.ID)
+}
+
+func (l *ApplicationLogic) GetAdApplications(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]application.RespApplication, error) {
+	currentAd, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ad")
+	}
+
+	if currentAd.Info.OwnerID != utils.GetUserIDFromContext(ctx) {
+		return nil, application.ErrNotOwner
+	}
+
+	return l.repo.GetAdApplications(ctx, adID, limit, offset)
+}
+
+func (l *ApplicationLogic) GetHistory(ctx context.Context, role string, limit int, offset int) ([]application.RespApplication, error) {
+	return l.repo.GetUserApplications(ctx, utils.GetUserIDFromContext(ctx), role, limit, offset)
+}
+
+func (l *ApplicationLogic) Accept(ctx context.Context, id uuid.UUID, reply string) (application.RespApplication, error) {
+	current, err := l.getOwnPendingApplication(ctx, id, reply)
+	if err != nil {
+		return application.RespApplication{}, err
+	}
+
+	adBefore, err := l.adRepo.GetAd(ctx, current.Info.AdID)
+	if err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	now := time.Now().Local()
+	rejected, err := l.repo.AcceptApplication(ctx, current.Info.ID, strings.TrimSpace(reply), now)
+	if err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to accept application")
+	}
+
+	result, err := l.repo.GetApplication(ctx, current.Info.ID)
+	if err != nil {
+		return application.RespApplication{}, err
+	}
+
+	// Accepting hands the animal over to the applicant, the ad is realised and the other pending
+	// applications are rejected in the same statement.
+	l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityApplication, result.Info.ID, current.Info, result.Info)
+
+	adAfter, err := l.adRepo.GetAd(ctx, current.Info.AdID)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to get realised ad for audit")
+	} else {
+		l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityAd, adAfter.Info.ID, adBefore.Info, adAfter.Info)
+	}
+
+	for _, before := range rejected {
+		after := before
+		after.Status = application.Rejected
+		after.Reply = application.AdoptedByAnotherReply
+		after.UpdatedAt = now
+		l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityApplication, before.ID, before, after)
+	}
+
+	return result, nil
+}
+
+func (l *ApplicationLogic) Reject(ctx context.Context, id uuid.UUID, reply string) (application.RespApplication, error) {
+	current, err := l.getOwnPendingApplication(ctx, id, reply)
+	if err != nil {
+		return application.RespApplication{}, err
+	}
+
+	if err = l.repo.RejectApplication(ctx, current.Info.ID, strings.TrimSpace(reply), time.Now().Local()); err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to reject application")
+	}
+
+	return l.repo.GetApplication(ctx, current.Info.ID)
+}
+
+func (l *ApplicationLogic) getOwnPendingApplication(ctx context.Context, id uuid.UUID, reply string) (application.RespApplication, error) {
+	current, err := l.repo.GetApplication(ctx, id)
+	if err != nil {
+		return application.RespApplication{}, errors.Wrap(err, "failed to get application")
+	}
+
+	if current.Info.OwnerID != utils.GetUserIDFromContext(ctx) {
+		return application.RespApplication{}, application.ErrNotOwner
+	}
+
+	if current.Info.Status != application.Pending {
+		return application.RespApplication{}, application.ErrNotPending
+	}
+
+	if utf8.RuneCountInString(strings.TrimSpace(reply)) > application.MaxMessageLength {
+		return application.RespApplication{}, application.ErrInvalidApplication
+	}
+
+	return current, nil
+}
+
+func validateForm(form application.ApplicationForm) error {
+	if utf8.RuneCountInString(form.Message) > application.MaxMessageLength {
+		return application.ErrInvalidApplication
+	}
+
+	if len(form.Answers) > application.MaxAnswersCount {
+		return application.ErrInvalidApplication
+	}
+
+	for question, answer := range form.Answers {
+		if strings.TrimSpace(question) == "" ||
+			utf8.RuneCountInString(question) > application.MaxAnswerLength ||
+			utf8.RuneCountInString(answer) > application.MaxAnswerLength {
+			return application.ErrInvalidApplication
+		}
+	}
+
+	if form.Message == "" && len(form.Answers) == 0 {
+		return application.ErrInvalidApplication
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"context"
+	goerrors "errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/application"
+)
+
+const (
+	selectApplication = `
+SELECT
+	Application.id, Application.ad_id, Application.owner_id, Application.adopter_id, Application.status,
+	Application.answers, Application.message, Application.reply, Application.created_at, Application.updated_at,
+	Ad.title,
+	Adopter.username,
+	Owner.username
+FROM Application
+JOIN Ad ON Application.ad_id = Ad.id
+JOIN MyUser AS Adopter ON Application.adopter_id = Adopter.id
+JOIN MyUser AS Owner ON Application.owner_id = Owner.id
+`
+	createApplication      = `INSERT INTO Application(id, ad_id, owner_id, adopter_id, status, answers, message, reply, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+	getApplication         = selectApplication + `WHERE Application.id = $1;`
+	getAdApplications      = selectApplication + `WHERE Application.ad_id = $1 ORDER BY Application.created_at DESC LIMIT $2 OFFSET $3;`
+	getOwnerApplications   = selectApplication + `WHERE Application.owner_id = $1 ORDER BY Application.updated_at DESC LIMIT $2 OFFSET $3;`
+	getAdopterApplications = selectApplication + `WHERE Application.adopter_id = $1 ORDER BY Application.updated_at DESC LIMIT $2 OFFSET $3;`
+	rejectApplication      = `UPDATE Application SET status = 'R', reply = $2, updated_at = $3 WHERE id = $1 AND status = 'P';`
+	acceptApplication      = `
+WITH accepted AS (
+	UPDATE Application SET status = 'A', reply = $2, updated_at = $3
+	WHERE id = $1 AND status = 'P' AND EXISTS (SELECT 1 FROM Ad WHERE Ad.id = Application.ad_id AND Ad.status = 'A')
+	RETURNING ad_id
+), realised AS (
+	UPDATE Ad SET status = 'R', updated_at = $3 WHERE id = (SELECT ad_id FROM accepted)
+), pending AS (
+	SELECT * FROM Application WHERE ad_id = (SELECT ad_id FROM accepted) AND id <> $1 AND status = 'P'
+), rejected AS (
+	UPDATE Application SET status = 'R', reply = $4, updated_at = $3
+	WHERE id IN (SELECT id FROM pending) AND status = 'P'
+	RETURNING id, ad_id, adopter_id
+), notified AS (
+	INSERT INTO SearchNotification(id, user_id, kind, application_id, ad_id, created_at)
+	SELECT gen_random_uuid(), adopter_id, 'A', id, ad_id, $3 FROM rejected
+)
+SELECT
+	(SELECT COUNT(*) FROM accepted),
+	(SELECT COALESCE(json_agg(pending), '[]') FROM pending WHERE id IN (SELECT id FROM rejected));
+`
+)
+
+type ApplicationPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewApplicationPostgres(db pgxtype.Querier) *ApplicationPostgres {
+	return &ApplicationPostgres{db: db}
+}
+
+func (repo *ApplicationPostgres) CreateApplication(ctx context.Context, row application.Application) error {
+	if _, err := repo.db.Exec(ctx, createApplication, row.ID, row.AdID, row.OwnerID, row.AdopterID, row.Status, row.Answers, row.Message, row.Reply, row.CreatedAt, row.UpdatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		if strings.Contains(err.Error(), "violates unique constraint") {
+			return application.ErrAlreadyApplied
+		}
+		return errors.Wrap(err, "failed to create application in postgres")
+	}
+
+	return nil
+}
+
+func (repo *ApplicationPostgres) GetApplication(ctx context.Context, id uuid.UUID) (application.RespApplication, error) {
+	result, err := scanApplication(repo.db.QueryRow(ctx, getApplication, id))
+	if err != nil {
+		if goerrors.Is(err, pgx.ErrNoRows) {
+			return result, application.ErrApplicationNotFound
+		}
+		return result, errors.Wrap(err, "failed to get application from postgres")
+	}
+
+	return result, nil
+}
+
+func (repo *ApplicationPostgres) GetAdApplications(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]application.RespApplication, error) {
+	return repo.getApplications(ctx, getAdApplications, adID, limit, offset)
+}
+
+func (repo *ApplicationPostgres) GetUserApplications(ctx context.Context, userID uuid.UUID, role string, limit int, offset int) ([]application.RespApplication, error) {
+	query := getAdopterApplications
+	if role == application.AsOwner {
+		query = getOwnerApplications
+	}
+
+	return repo.getApplications(ctx, query, userID, limit, offset)
+}
+
+func (repo *ApplicationPostgres) RejectApplication(ctx context.Context, id uuid.UUID, reply string, now time.Time) error {
+	tag, err := repo.db.Exec(ctx, rejectApplication, id, reply, now)
+	if err != nil {
+		return errors.Wrap(err, "failed to reject application in postgres")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return application.ErrNotPending
+	}
+
+	return nil
+}
+
+// AcceptApplication returns the other pending applications of the ad as they were before they got rejected.
+func (repo *ApplicationPostgres) AcceptApplication(ctx context.Context, id uuid.UUID, reply string, now time.Time) ([]application.Application, error) {
+	var accepted int
+	rejected := make([]application.Application, 0)
+	if err := repo.db.QueryRow(ctx, acceptApplication, id, reply, now, application.AdoptedByAnotherReply).Scan(&accepted, &rejected); err != nil {
+		return nil, errors.Wrap(err, "failed to accept application in postgres")
+	}
+
+	if accepted == 0 {
+		return nil, application.ErrNotPending
+	}
+
+	return rejected, nil
+}
+
+func (repo *ApplicationPostgres) getApplications(ctx context.Context, query string, args ...interface{}) ([]application.RespApplication, error) {
+	result := make([]application.RespApplication, 0)
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get applications from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := scanApplication(rows)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse application")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func scanApplication(row pgx.Row) (application.RespApplication, error) {
+	var result application.RespApplication
+	err := row.Scan(
+		&result.Info.ID, &result.Info.AdID, &result.Info.OwnerID, &result.Info.AdopterID, &result.Info.Status,
+		&result.Info.Answers, &result.Info.Message, &result.Info.Reply, &result.Info.CreatedAt, &result.Info.UpdatedAt,
+		&result.ExtraInfo.AdTitle, &result.ExtraInfo.AdopterUsername, &result.ExtraInfo.OwnerUsername,
+	)
+
+	return result, err
+}
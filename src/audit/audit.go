@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid audit filter")
+)
+
+const (
+	ActionCreate       = "create"
+	ActionDelete       = "delete"
+	ActionStatusChange = "status_change"
+	ActionScreen       = "screen"
+	ActionRoleChange   = "role_change"
+
+	EntityAnimal      = "animal"
+	EntityBreed       = "breed"
+	EntityRegion      = "region"
+	EntityLocality    = "locality"
+	EntityAd          = "ad"
+	EntityApplication = "application"
+	EntityUser        = "user"
+)
+
+var EntityTypes = []string{EntityAnimal, EntityBreed, EntityRegion, EntityLocality, EntityAd, EntityApplication, EntityUser}
+
+// Entry is one record of the append-only audit log. Before and After are JSON snapshots of the entity,
+// null when it did not exist. ActorID is nil for the actions done without a signed in user.
+type Entry struct {
+	ID            uuid.UUID       `json:"id"`
+	ActorID       uuid.UUID       `json:"actor_id"`
+	ActorUsername string          `json:"actor_username"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      uuid.UUID       `json:"entity_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	RequestID     string          `json:"request_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type Filter struct {
+	ActorUsername *string
+	EntityType    *string
+	EntityID      *uuid.UUID
+	From          *time.Time
+	To            *time.Time
+	Limit         int
+	Offset        int
+}
+
+type AuditRepo interface {
+	AddEntry(ctx context.Context, entry Entry) error
+	GetEntries(ctx context.Context, filter Filter) ([]Entry, error)
+}
+
+type AuditLogic interface {
+	Record(ctx context.Context, action string, entityType string, entityID uuid.UUID, before any, after any)
+	GetEntries(ctx context.Context, filter Filter) ([]Entry, error)
+}
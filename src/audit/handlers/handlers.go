@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/config"
+	"pet_adopter/src/utils"
+)
+
+type AuditHandler struct {
+	logic audit.AuditLogic
+	cfg   config.AdConfig
+}
+
+func NewAuditHandler(logic audit.AuditLogic, cfg config.AdConfig) *AuditHandler {
+	return &AuditHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type EntriesResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// GetEntries lists the audit log, the newest first. Optional query params: actor (username), entity_type,
+// entity_id and the from/to time range in RFC 3339, from inclusive and to exclusive.
+func (h *AuditHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	filter, err := getFilterFromQuery(query)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse audit filter")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	filter.Limit, filter.Offset, err = utils.GetPaginationFromQuery(query, h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.logic.GetEntries(ctx, filter)
+	if err != nil {
+		handleAuditError(ctx, w, err)
+		return
+	}
+
+	result := EntriesResponse{Entries: entries}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func getFilterFromQuery(query url.Values) (audit.Filter, error) {
+	result := audit.Filter{}
+
+	if actor := query.Get("actor"); actor != "" {
+		result.ActorUsername = &actor
+	}
+
+	if entityType := query.Get("entity_type"); entityType != "" {
+		result.EntityType = &entityType
+	}
+
+	if entityIDString := query.Get("entity_id"); entityIDString != "" {
+		entityID, err := uuid.FromString(entityIDString)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse entity_id")
+		}
+		result.EntityID = &entityID
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **time.Time
+	}{
+		{"from", &result.From},
+		{"to", &result.To},
+	} {
+		boundString := query.Get(bound.name)
+		if boundString == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, boundString)
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to parse %s", bound.name)
+		}
+		*bound.value = &parsed
+	}
+
+	return result, nil
+}
+
+func handleAuditError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, audit.ErrInvalidFilter):
+		utils.LogError(ctx, err, "invalid audit filter")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	default:
+		utils.LogError(ctx, err, "failed to get audit log")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
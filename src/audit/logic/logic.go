@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/utils"
+)
+
+type AuditLogic struct {
+	repo audit.AuditRepo
+}
+
+func NewAuditLogic(repo audit.AuditRepo) AuditLogic {
+	return AuditLogic{repo: repo}
+}
+
+// Record appends the action of the user from the context to the audit log, nil snapshots are stored as NULL.
+// The action itself has already been done by then, so a failed write is logged instead of returned.
+func (l *AuditLogic) Record(ctx context.Context, action string, entityType string, entityID uuid.UUID, before any, after any) {
+	entry := audit.Entry{
+		ID:            uuid.NewV4(),
+		ActorID:       utils.GetUserIDFromContext(ctx),
+		ActorUsername: utils.GetUsernameFromContext(ctx),
+		Action:        action,
+		EntityType:    entityType,
+		EntityID:      entityID,
+		RequestID:     utils.GetRequestIDFromContext(ctx),
+		CreatedAt:     time.Now().Local(),
+	}
+
+	var err error
+	if entry.Before, err = snapshot(before); err == nil {
+		entry.After, err = snapshot(after)
+	}
+	if err == nil {
+		err = l.repo.AddEntry(ctx, entry)
+	}
+
+	if err != nil {
+		utils.LogError(ctx, err, fmt.Sprintf("failed to record %s of %s %s in audit log", action, entityType, entityID))
+	}
+}
+
+func (l *AuditLogic) GetEntries(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	if filter.EntityType != nil && !slices.Contains(audit.EntityTypes, *filter.EntityType) {
+		return nil, audit.ErrInvalidFilter
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, audit.ErrInvalidFilter
+	}
+
+	return l.repo.GetEntries(ctx, filter)
+}
+
+func snapshot(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(value)
+}
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
+)
+
+const (
+	addEntry = `
+INSERT INTO AuditLog(id, actor_id, actor_username, action, entity_type, entity_id, before, after, request_id, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+`
+	getEntries = `
+SELECT id, actor_id, actor_username, action, entity_type, entity_id, before, after, request_id, created_at
+FROM AuditLog
+%s
+ORDER BY created_at DESC, id
+LIMIT $%d OFFSET $%d;
+`
+)
+
+type AuditPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewAuditPostgres(db pgxtype.Querier) *AuditPostgres {
+	return &AuditPostgres{db: db}
+}
+
+func (repo *AuditPostgres) AddEntry(ctx context.Context, entry audit.Entry) error {
+	var actorID any
+	if entry.ActorID != uuid.Nil {
+		actorID = entry.ActorID
+	}
+
+	if _, err := repo.db.Exec(ctx, addEntry,
+		entry.ID, actorID, entry.ActorUsername, entry.Action, entry.EntityType, entry.EntityID,
+		[]byte(entry.Before), []byte(entry.After), entry.RequestID, entry.CreatedAt,
+	); err != nil {
+		return errors.Wrap(err, "failed to add audit entry in postgres")
+	}
+
+	return nil
+}
+
+func (repo *AuditPostgres) GetEntries(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	result := make([]audit.Entry, 0)
+
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorUsername != nil {
+		addCondition("actor_username = $%d", *filter.ActorUsername)
+	}
+	if filter.EntityType != nil {
+		addCondition("entity_type = $%d", *filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		addCondition("entity_id = $%d", *filter.EntityID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := repo.db.Query(ctx, fmt.Sprintf(getEntries, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get audit entries from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row audit.Entry
+		var actorID []byte
+		if err = rows.Scan(
+			&row.ID, &actorID, &row.ActorUsername, &row.Action, &row.EntityType, &row.EntityID,
+			&row.Before, &row.After, &row.RequestID, &row.CreatedAt,
+		); err != nil {
+			return result, errors.Wrap(err, "failed to parse audit entry")
+		}
+		row.ActorID = uuid.FromBytesOrNil(actorID)
+		result = append(result, row)
+	}
+
+	return result, nil
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 
 	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
 	"pet_adopter/src/breed"
 )
 
 type BreedLogic struct {
-	repo breed.BreedRepo
+	repo     breed.BreedRepo
+	auditLog audit.AuditLogic
 }
 
-func NewBreedLogic(repo breed.BreedRepo) BreedLogic {
-	return BreedLogic{repo: repo}
+func NewBreedLogic(repo breed.BreedRepo, auditLog audit.AuditLogic) BreedLogic {
+	return BreedLogic{repo: repo, auditLog: auditLog}
 }
 
 func (logic *BreedLogic) GetBreeds(ctx context.Context) ([]breed.Breed, error) {
@@ -29,9 +31,24 @@ func (logic *BreedLogic) GetBreedsByAnimalID(ctx context.Context, animalID uuid.
 
 func (logic *BreedLogic) AddBreed(ctx context.Context, name string, animalID uuid.UUID) (breed.Breed, error) {
 	result := breed.Breed{ID: uuid.NewV4(), Name: name, AnimalID: animalID}
-	return result, logic.repo.AddBreed(ctx, result)
+	if err := logic.repo.AddBreed(ctx, result); err != nil {
+		return result, err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionCreate, audit.EntityBreed, result.ID, nil, result)
+	return result, nil
 }
 
 func (logic *BreedLogic) RemoveBreedByID(ctx context.Context, id uuid.UUID) error {
-	return logic.repo.RemoveBreedByID(ctx, id)
+	before, err := logic.repo.GetBreedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = logic.repo.RemoveBreedByID(ctx, id); err != nil {
+		return err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionDelete, audit.EntityBreed, id, before, nil)
+	return nil
 }
@@ -18,6 +18,8 @@ const (
 var (
 	ErrDescriptionNotFound = errors.New("description not found")
 
+	ScreenAdPrompt = "Проверь объявление о передаче животного на мошенничество, спам, оскорбления и запрещённый контент. Ответь по шаблону: {\"ok\":true} если объявление допустимо или {\"ok\":false,\"reason\":\"причина\"} если нет. В ответе напиши только результат и ничего лишнего. Объявление:\n"
+
 	DescribePhotoPrompt = "Определи цвет окраса животного в формате RGB и дай ответ по шаблону: {\"color\":\"243 12 123\"} формат RGB. Если на фото нет животного или сложно распознать - напиши пустой json {}. Если На фото несколько животных - выбери любого на свой выбор. В ответе напиши только результат и ничего лишнего."
 )
 
@@ -29,6 +31,12 @@ type ContentItem struct {
 	ImageURL string `json:"image_url,omitempty"`
 }
 
+// Verdict is the answer to ScreenAdPrompt.
+type Verdict struct {
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason"`
+}
+
 type Description struct {
 	Color string `json:"color"`
 }
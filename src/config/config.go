@@ -14,18 +14,24 @@ type UserIDKey string
 type UsernameKey string
 
 const (
-	LoggerContextKey   LoggerKey = "logger"
-	UserIDContextKey   UserIDKey = "userID"
-	UsernameContextKey UserIDKey = "username"
+	LoggerContextKey    LoggerKey = "logger"
+	UserIDContextKey    UserIDKey = "userID"
+	UsernameContextKey  UserIDKey = "username"
+	UserRoleContextKey  UserIDKey = "userRole"
+	SessionIDContextKey UserIDKey = "sessionID"
+	RequestIDContextKey LoggerKey = "requestID"
 )
 
 type Config struct {
-	Main       MainConfig       `yaml:"main"`
-	Session    SessionConfig    `yaml:"session"`
-	Validation ValidationConfig `yaml:"validation"`
-	Ad         AdConfig         `yaml:"ad"`
-	ChatGPT    ChatGPTConfig    `yaml:"chat_gpt"`
-	Color      ColorConfig      `yaml:"color"`
+	Main        MainConfig        `yaml:"main"`
+	Session     SessionConfig     `yaml:"session"`
+	Password    PasswordConfig    `yaml:"password"`
+	Validation  ValidationConfig  `yaml:"validation"`
+	Ad          AdConfig          `yaml:"ad"`
+	ChatGPT     ChatGPTConfig     `yaml:"chat_gpt"`
+	Color       ColorConfig       `yaml:"color"`
+	Storage     StorageConfig     `yaml:"storage"`
+	SavedSearch SavedSearchConfig `yaml:"saved_search"`
 }
 
 type MainConfig struct {
@@ -37,12 +43,29 @@ type MainConfig struct {
 	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
 }
 
+// TokenConfig describes a random token, Entropy is the number of random bytes before encoding.
+type TokenConfig struct {
+	Entropy  int    `yaml:"entropy"`
+	Encoding string `yaml:"encoding"`
+}
+
 type SessionConfig struct {
-	RefreshTokenLength    int           `yaml:"refresh_token_length"`
-	AccessTokenLength     int           `yaml:"access_token_length"`
+	RefreshToken          TokenConfig   `yaml:"refresh_token"`
+	AccessToken           TokenConfig   `yaml:"access_token"`
 	AccessTokenLifeTime   time.Duration `yaml:"access_token_life_time"`
+	RefreshTokenLifeTime  time.Duration `yaml:"refresh_token_life_time"`
 	AccessTokenCookieName string        `yaml:"access_token_cookie_name"`
 	ProtectedCookies      bool          `yaml:"protected_cookies"`
+	UsernameParamSunset   time.Time     `yaml:"username_param_sunset"`
+}
+
+// PasswordConfig holds the argon2id cost, Memory is in KiB. Hashes made with another cost are redone on login.
+type PasswordConfig struct {
+	Memory      uint32 `yaml:"memory"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
 }
 
 type ValidationConfig struct {
@@ -53,18 +76,50 @@ type ValidationConfig struct {
 }
 
 type AdConfig struct {
-	MaxPrice            int           `yaml:"max_price"`
-	DefaultSearchLimit  int           `yaml:"default_search_limit"`
-	DefaultSearchOffset int           `yaml:"default_search_offset"`
-	MaxSearchLimit      int           `yaml:"max_search_limit"`
-	AdPhotoConfig       AdPhotoConfig `yaml:"photo"`
-	CreateFormFieldName string        `yaml:"create_form_field_name"`
+	MaxPrice            int                `yaml:"max_price"`
+	DefaultSearchLimit  int                `yaml:"default_search_limit"`
+	DefaultSearchOffset int                `yaml:"default_search_offset"`
+	MaxSearchLimit      int                `yaml:"max_search_limit"`
+	AdPhotoConfig       AdPhotoConfig      `yaml:"photo"`
+	CreateFormFieldName string             `yaml:"create_form_field_name"`
+	PriceBuckets        []int              `yaml:"price_buckets"`
+	Map                 AdMapConfig        `yaml:"map"`
+	Moderation          AdModerationConfig `yaml:"moderation"`
+}
+
+// AdModerationConfig enables pre-publication moderation: new and edited ads stay pending until they pass
+// the automatic checks or a moderator approves them.
+type AdModerationConfig struct {
+	PrePublication  bool     `yaml:"pre_publication"`
+	BannedWords     []string `yaml:"banned_words"`
+	ContactsPattern string   `yaml:"contacts_pattern"`
+	UseLLM          bool     `yaml:"use_llm"`
+}
+
+// AdMapConfig controls map clustering: below LocalityZoom ads are grouped into a grid of GridSize cells per
+// map tile, from LocalityZoom on they are grouped by locality.
+type AdMapConfig struct {
+	GridSize     int `yaml:"grid_size"`
+	LocalityZoom int `yaml:"locality_zoom"`
+	MaxZoom      int `yaml:"max_zoom"`
+	SampleSize   int `yaml:"sample_size"`
+	MaxClusters  int `yaml:"max_clusters"`
 }
 
 type AdPhotoConfig struct {
-	MaxFormDataSize  int64             `yaml:"max_form_data_size"`
-	FileTypes        map[string]string `yaml:"file_types"`
-	RequestFieldName string            `yaml:"request_field_name"`
+	MaxFormDataSize  int64                 `yaml:"max_form_data_size"`
+	FileTypes        map[string]string     `yaml:"file_types"`
+	RequestFieldName string                `yaml:"request_field_name"`
+	MaxPhotosPerAd   int                   `yaml:"max_photos_per_ad"`
+	Processing       PhotoProcessingConfig `yaml:"processing"`
+}
+
+type PhotoProcessingConfig struct {
+	MaxSourcePixels    int64 `yaml:"max_source_pixels"`
+	MaxDimension       int   `yaml:"max_dimension"`
+	MediumDimension    int   `yaml:"medium_dimension"`
+	ThumbnailDimension int   `yaml:"thumbnail_dimension"`
+	JPEGQuality        int   `yaml:"jpeg_quality"`
 }
 
 type ChatGPTConfig struct {
@@ -78,6 +133,26 @@ type ColorConfig struct {
 	MaxSumDistance  int64 `yaml:"max_sum_distance"`
 }
 
+type StorageConfig struct {
+	Type string   `yaml:"type"`
+	S3   S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint     string        `yaml:"endpoint"`
+	Region       string        `yaml:"region"`
+	Bucket       string        `yaml:"bucket"`
+	UsePathStyle bool          `yaml:"use_path_style"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+type SavedSearchConfig struct {
+	MaxPerUser    int           `yaml:"max_per_user"`
+	CheckInterval time.Duration `yaml:"check_interval"`
+	CheckOverlap  time.Duration `yaml:"check_overlap"`
+	BatchSize     int           `yaml:"batch_size"`
+}
+
 func MustLoadConfig(path string, logger *slog.Logger) *Config {
 	cfg := &Config{}
 
@@ -0,0 +1,75 @@
+package conversation
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+)
+
+var (
+	ErrThreadNotFound = errors.New("thread not found")
+	ErrNotParticipant = errors.New("not a thread participant")
+	ErrNotOwner       = errors.New("not owner")
+	ErrThreadClosed   = errors.New("thread is closed")
+	ErrOwnAd          = errors.New("can not open thread on own ad")
+	ErrAdNotActual    = errors.New("ad is not actual")
+	ErrAdNotRealised  = errors.New("ad is not realised")
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+const (
+	Open   = "O"
+	Closed = "C"
+
+	MaxMessageLength = 4096
+)
+
+type Thread struct {
+	ID        uuid.UUID `json:"id"`
+	AdID      uuid.UUID `json:"ad_id"`
+	OwnerID   uuid.UUID `json:"owner_id"`
+	AdopterID uuid.UUID `json:"adopter_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ThreadInfo struct {
+	AdTitle           string `json:"ad_title"`
+	CompanionUsername string `json:"companion_username"`
+	UnreadCount       int    `json:"unread_count"`
+}
+
+type RespThread struct {
+	Info      Thread     `json:"info"`
+	ExtraInfo ThreadInfo `json:"extra_info"`
+}
+
+type Message struct {
+	ID        uuid.UUID `json:"id"`
+	ThreadID  uuid.UUID `json:"thread_id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConversationRepo interface {
+	CreateThread(ctx context.Context, thread Thread) error
+	GetThread(ctx context.Context, id uuid.UUID) (Thread, error)
+	GetThreadByAdAndAdopter(ctx context.Context, adID uuid.UUID, adopterID uuid.UUID) (Thread, error)
+	GetThreads(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]RespThread, error)
+	SetThreadStatus(ctx context.Context, id uuid.UUID, status string, now time.Time) error
+	AddMessage(ctx context.Context, message Message) error
+	GetMessages(ctx context.Context, threadID uuid.UUID, limit int, offset int) ([]Message, error)
+	MarkRead(ctx context.Context, threadID uuid.UUID, userID uuid.UUID, now time.Time) error
+}
+
+type ConversationLogic interface {
+	OpenThread(ctx context.Context, adID uuid.UUID, text string) (Thread, error)
+	GetThreads(ctx context.Context, limit int, offset int) ([]RespThread, error)
+	GetMessages(ctx context.Context, threadID uuid.UUID, limit int, offset int) ([]Message, error)
+	SendMessage(ctx context.Context, threadID uuid.UUID, text string) (Message, error)
+	CloseThread(ctx context.Context, threadID uuid.UUID) (Thread, error)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+	"pet_adopter/src/conversation"
+	"pet_adopter/src/utils"
+)
+
+type ConversationHandler struct {
+	logic conversation.ConversationLogic
+	cfg   config.AdConfig
+}
+
+func NewConversationHandler(logic conversation.ConversationLogic, cfg config.AdConfig) *ConversationHandler {
+	return &ConversationHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type OpenThreadRequest struct {
+	Text string `json:"text"`
+}
+
+type ThreadResponse struct {
+	Thread conversation.Thread `json:"thread"`
+}
+
+func (h *ConversationHandler) OpenThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req OpenThreadRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	thread, err := h.logic.OpenThread(ctx, adID, req.Text)
+	if err != nil {
+		handleConversationError(ctx, w, err)
+		return
+	}
+
+	result := ThreadResponse{Thread: thread}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type GetThreadsResponse struct {
+	Threads []conversation.RespThread `json:"threads"`
+}
+
+func (h *ConversationHandler) GetThreads(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	threads, err := h.logic.GetThreads(ctx, limit, offset)
+	if err != nil {
+		handleConversationError(ctx, w, err)
+		return
+	}
+
+	result := GetThreadsResponse{Threads: threads}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type GetMessagesResponse struct {
+	Messages []conversation.Message `json:"messages"`
+}
+
+func (h *ConversationHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid thread id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.logic.GetMessages(ctx, threadID, limit, offset)
+	if err != nil {
+		handleConversationError(ctx, w, err)
+		return
+	}
+
+	result := GetMessagesResponse{Messages: messages}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type SendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+type SendMessageResponse struct {
+	Message conversation.Message `json:"message"`
+}
+
+func (h *ConversationHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid thread id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req SendMessageRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.logic.SendMessage(ctx, threadID, req.Text)
+	if err != nil {
+		handleConversationError(ctx, w, err)
+		return
+	}
+
+	result := SendMessageResponse{Message: message}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ConversationHandler) CloseThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid thread id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	thread, err := h.logic.CloseThread(ctx, threadID)
+	if err != nil {
+		handleConversationError(ctx, w, err)
+		return
+	}
+
+	result := ThreadResponse{Thread: thread}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleConversationError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, ad.ErrAdNotFound):
+		utils.LogError(ctx, err, "ad not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, conversation.ErrThreadNotFound):
+		utils.LogError(ctx, err, "thread not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, conversation.ErrNotParticipant), goerrors.Is(err, conversation.ErrNotOwner):
+		utils.LogError(ctx, err, "access denied")
+		http.Error(w, utils.NotFound, http.StatusForbidden)
+	case goerrors.Is(err, conversation.ErrThreadClosed),
+		goerrors.Is(err, ad.ErrModerated),
+		goerrors.Is(err, conversation.ErrOwnAd),
+		goerrors.Is(err, conversation.ErrAdNotActual),
+		goerrors.Is(err, conversation.ErrAdNotRealised),
+		goerrors.Is(err, conversation.ErrInvalidMessage):
+		utils.LogError(ctx, err, "invalid conversation operation")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	default:
+		utils.LogError(ctx, err, "failed to perform operation")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,191 @@
+package logic
+
+import (
+	"context"
+	goerrors "errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/conversation"
+	"pet_adopter/src/utils"
+)
+
+type ConversationLogic struct {
+	repo   conversation.ConversationRepo
+	adRepo ad.AdRepo
+}
+
+func NewConversationLogic(repo conversation.ConversationRepo, adRepo ad.AdRepo) ConversationLogic {
+	return ConversationLogic{
+		repo:   repo,
+		adRepo: adRepo,
+	}
+}
+
+func (l *ConversationLogic) OpenThread(ctx context.Context, adID uuid.UUID, text string) (conversation.Thread, error) {
+	now := time.Now().Local()
+	userID := utils.GetUserIDFromContext(ctx)
+
+	currentAd, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return conversation.Thread{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	if !ad.IsVisible(ctx, currentAd.Info) {
+		return conversation.Thread{}, ad.ErrAdNotFound
+	}
+
+	if currentAd.Info.OwnerID == userID {
+		return conversation.Thread{}, conversation.ErrOwnAd
+	}
+
+	thread, err := l.repo.GetThreadByAdAndAdopter(ctx, adID, userID)
+	if err != nil && !goerrors.Is(err, conversation.ErrThreadNotFound) {
+		return conversation.Thread{}, errors.Wrap(err, "failed to get thread")
+	}
+
+	if goerrors.Is(err, conversation.ErrThreadNotFound) {
+		if currentAd.Info.Status != ad.Actual {
+			return conversation.Thread{}, conversation.ErrAdNotActual
+		}
+
+		thread = conversation.Thread{
+			ID:        uuid.NewV4(),
+			AdID:      adID,
+			OwnerID:   currentAd.Info.OwnerID,
+			AdopterID: userID,
+			Status:    conversation.Open,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err = l.repo.CreateThread(ctx, thread); err != nil {
+			return conversation.Thread{}, errors.Wrap(err, "failed to create thread")
+		}
+	}
+
+	if strings.TrimSpace(text) != "" {
+		if _, err = l.sendMessage(ctx, thread, currentAd.Info, text); err != nil {
+			return conversation.Thread{}, err
+		}
+	}
+
+	return l.repo.GetThread(ctx, thread.ID)
+}
+
+func (l *ConversationLogic) GetThreads(ctx context.Context, limit int, offset int) ([]conversation.RespThread, error) {
+	return l.repo.GetThreads(ctx, utils.GetUserIDFromContext(ctx), limit, offset)
+}
+
+func (l *ConversationLogic) GetMessages(ctx context.Context, threadID uuid.UUID, limit int, offset int) ([]conversation.Message, error) {
+	thread, err := l.getOwnThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := l.repo.GetMessages(ctx, thread.ID, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get messages")
+	}
+
+	if err = l.repo.MarkRead(ctx, thread.ID, utils.GetUserIDFromContext(ctx), time.Now().Local()); err != nil {
+		return nil, errors.Wrap(err, "failed to mark thread as read")
+	}
+
+	return messages, nil
+}
+
+func (l *ConversationLogic) SendMessage(ctx context.Context, threadID uuid.UUID, text string) (conversation.Message, error) {
+	thread, err := l.getOwnThread(ctx, threadID)
+	if err != nil {
+		return conversation.Message{}, err
+	}
+
+	currentAd, err := l.adRepo.GetAd(ctx, thread.AdID)
+	if err != nil {
+		return conversation.Message{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	return l.sendMessage(ctx, thread, currentAd.Info, text)
+}
+
+func (l *ConversationLogic) CloseThread(ctx context.Context, threadID uuid.UUID) (conversation.Thread, error) {
+	thread, err := l.getOwnThread(ctx, threadID)
+	if err != nil {
+		return conversation.Thread{}, err
+	}
+
+	if thread.OwnerID != utils.GetUserIDFromContext(ctx) {
+		return conversation.Thread{}, conversation.ErrNotOwner
+	}
+
+	currentAd, err := l.adRepo.GetAd(ctx, thread.AdID)
+	if err != nil {
+		return conversation.Thread{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	if currentAd.Info.Status != ad.Realised {
+		return conversation.Thread{}, conversation.ErrAdNotRealised
+	}
+
+	if err = l.repo.SetThreadStatus(ctx, thread.ID, conversation.Closed, time.Now().Local()); err != nil {
+		return conversation.Thread{}, errors.Wrap(err, "failed to close thread")
+	}
+
+	return l.repo.GetThread(ctx, thread.ID)
+}
+
+func (l *ConversationLogic) getOwnThread(ctx context.Context, threadID uuid.UUID) (conversation.Thread, error) {
+	thread, err := l.repo.GetThread(ctx, threadID)
+	if err != nil {
+		return conversation.Thread{}, errors.Wrap(err, "failed to get thread")
+	}
+
+	userID := utils.GetUserIDFromContext(ctx)
+	if thread.OwnerID != userID && thread.AdopterID != userID {
+		return conversation.Thread{}, conversation.ErrNotParticipant
+	}
+
+	return thread, nil
+}
+
+func (l *ConversationLogic) sendMessage(ctx context.Context, thread conversation.Thread, threadAd ad.Ad, text string) (conversation.Message, error) {
+	now := time.Now().Local()
+
+	if thread.Status == conversation.Closed {
+		return conversation.Message{}, conversation.ErrThreadClosed
+	}
+
+	// A moderated ad is out of reach until a moderator publishes it again, so is its thread.
+	if slices.Contains(ad.ModeratedStatuses, threadAd.Status) {
+		return conversation.Message{}, ad.ErrModerated
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > conversation.MaxMessageLength {
+		return conversation.Message{}, conversation.ErrInvalidMessage
+	}
+
+	message := conversation.Message{
+		ID:        uuid.NewV4(),
+		ThreadID:  thread.ID,
+		SenderID:  utils.GetUserIDFromContext(ctx),
+		Text:      text,
+		CreatedAt: now,
+	}
+
+	if err := l.repo.AddMessage(ctx, message); err != nil {
+		return conversation.Message{}, errors.Wrap(err, "failed to add message")
+	}
+
+	if err := l.repo.MarkRead(ctx, thread.ID, message.SenderID, now); err != nil {
+		return conversation.Message{}, errors.Wrap(err, "failed to mark thread as read")
+	}
+
+	return message, nil
+}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	goerrors "errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/conversation"
+)
+
+const (
+	createThread            = `INSERT INTO Thread(id, ad_id, owner_id, adopter_id, status, adopter_read_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6, $7);`
+	getThread               = `SELECT id, ad_id, owner_id, adopter_id, status, created_at, updated_at FROM Thread WHERE id = $1;`
+	getThreadByAdAndAdopter = `SELECT id, ad_id, owner_id, adopter_id, status, created_at, updated_at FROM Thread WHERE ad_id = $1 AND adopter_id = $2;`
+	getThreads              = `
+SELECT
+	Thread.id, Thread.ad_id, Thread.owner_id, Thread.adopter_id, Thread.status, Thread.created_at, Thread.updated_at,
+	Ad.title,
+	Companion.username,
+	(
+		SELECT COUNT(*)
+		FROM Message
+		WHERE Message.thread_id = Thread.id
+			AND Message.sender_id <> $1
+			AND Message.created_at > COALESCE(
+				CASE WHEN Thread.owner_id = $1 THEN Thread.owner_read_at ELSE Thread.adopter_read_at END,
+				'-infinity'::timestamptz
+			)
+	) AS unread_count
+FROM Thread
+JOIN Ad ON Thread.ad_id = Ad.id
+JOIN MyUser AS Companion ON Companion.id = CASE WHEN Thread.owner_id = $1 THEN Thread.adopter_id ELSE Thread.owner_id END
+WHERE Thread.owner_id = $1 OR Thread.adopter_id = $1
+ORDER BY Thread.updated_at DESC
+LIMIT $2 OFFSET $3;
+`
+	setThreadStatus = `UPDATE Thread SET status = $1, updated_at = $2 WHERE id = $3;`
+	addMessage      = `
+WITH message AS (
+	INSERT INTO Message(id, thread_id, sender_id, text, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING thread_id
+)
+UPDATE Thread SET updated_at = $5 WHERE id = (SELECT thread_id FROM message);
+`
+	getMessages = `SELECT id, thread_id, sender_id, text, created_at FROM Message WHERE thread_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;`
+	markRead    = `
+UPDATE Thread
+SET
+	owner_read_at = CASE WHEN owner_id = $2 THEN $3 ELSE owner_read_at END,
+	adopter_read_at = CASE WHEN adopter_id = $2 THEN $3 ELSE adopter_read_at END
+WHERE id = $1;
+`
+)
+
+type ConversationPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewConversationPostgres(db pgxtype.Querier) *ConversationPostgres {
+	return &ConversationPostgres{db: db}
+}
+
+func (repo *ConversationPostgres) CreateThread(ctx context.Context, thread conversation.Thread) error {
+	if _, err := repo.db.Exec(ctx, createThread, thread.ID, thread.AdID, thread.OwnerID, thread.AdopterID, thread.Status, thread.CreatedAt, thread.UpdatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		return errors.Wrap(err, "failed to create thread in postgres")
+	}
+
+	return nil
+}
+
+func (repo *ConversationPostgres) GetThread(ctx context.Context, id uuid.UUID) (conversation.Thread, error) {
+	return repo.getThread(ctx, getThread, id)
+}
+
+func (repo *ConversationPostgres) GetThreadByAdAndAdopter(ctx context.Context, adID uuid.UUID, adopterID uuid.UUID) (conversation.Thread, error) {
+	return repo.getThread(ctx, getThreadByAdAndAdopter, adID, adopterID)
+}
+
+func (repo *ConversationPostgres) GetThreads(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]conversation.RespThread, error) {
+	result := make([]conversation.RespThread, 0)
+
+	rows, err := repo.db.Query(ctx, getThreads, userID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get threads from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row conversation.RespThread
+		if err = rows.Scan(
+			&row.Info.ID, &row.Info.AdID, &row.Info.OwnerID, &row.Info.AdopterID, &row.Info.Status, &row.Info.CreatedAt, &row.Info.UpdatedAt,
+			&row.ExtraInfo.AdTitle, &row.ExtraInfo.CompanionUsername, &row.ExtraInfo.UnreadCount,
+		); err != nil {
+			return result, errors.Wrap(err, "failed to parse thread")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repo *ConversationPostgres) SetThreadStatus(ctx context.Context, id uuid.UUID, status string, now time.Time) error {
+	if _, err := repo.db.Exec(ctx, setThreadStatus, status, now, id); err != nil {
+		return errors.Wrap(err, "failed to set thread status in postgres")
+	}
+
+	return nil
+}
+
+func (repo *ConversationPostgres) AddMessage(ctx context.Context, message conversation.Message) error {
+	if _, err := repo.db.Exec(ctx, addMessage, message.ID, message.ThreadID, message.SenderID, message.Text, message.CreatedAt); err != nil {
+		return errors.Wrap(err, "failed to add message to postgres")
+	}
+
+	return nil
+}
+
+func (repo *ConversationPostgres) GetMessages(ctx context.Context, threadID uuid.UUID, limit int, offset int) ([]conversation.Message, error) {
+	result := make([]conversation.Message, 0)
+
+	rows, err := repo.db.Query(ctx, getMessages, threadID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get messages from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row conversation.Message
+		if err = rows.Scan(&row.ID, &row.ThreadID, &row.SenderID, &row.Text, &row.CreatedAt); err != nil {
+			return result, errors.Wrap(err, "failed to parse message")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repo *ConversationPostgres) MarkRead(ctx context.Context, threadID uuid.UUID, userID uuid.UUID, now time.Time) error {
+	if _, err := repo.db.Exec(ctx, markRead, threadID, userID, now); err != nil {
+		return errors.Wrap(err, "failed to mark thread as read in postgres")
+	}
+
+	return nil
+}
+
+func (repo *ConversationPostgres) getThread(ctx context.Context, query string, args ...interface{}) (conversation.Thread, error) {
+	result := conversation.Thread{}
+	if err := repo.db.QueryRow(ctx, query, args...).Scan(&result.ID, &result.AdID, &result.OwnerID, &result.AdopterID, &result.Status, &result.CreatedAt, &result.UpdatedAt); err != nil {
+		if goerrors.Is(err, pgx.ErrNoRows) {
+			return result, conversation.ErrThreadNotFound
+		}
+		return result, errors.Wrap(err, "failed to get thread from postgres")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,27 @@
+package favorite
+
+import (
+	"context"
+	"time"
+
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+type Favorite struct {
+	UserID    uuid.UUID `json:"user_id"`
+	AdID      uuid.UUID `json:"ad_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FavoriteRepo interface {
+	AddFavorite(ctx context.Context, favorite Favorite) error
+	RemoveFavorite(ctx context.Context, userID uuid.UUID, adID uuid.UUID) error
+	GetFavoriteAdIDs(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]uuid.UUID, error)
+}
+
+type FavoriteLogic interface {
+	AddFavorite(ctx context.Context, adID uuid.UUID) (ad.RespAd, error)
+	RemoveFavorite(ctx context.Context, adID uuid.UUID) (ad.RespAd, error)
+	GetFavorites(ctx context.Context, limit int, offset int) ([]ad.RespAd, error)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+	"pet_adopter/src/favorite"
+	"pet_adopter/src/utils"
+)
+
+type FavoriteHandler struct {
+	logic favorite.FavoriteLogic
+	cfg   config.AdConfig
+}
+
+func NewFavoriteHandler(logic favorite.FavoriteLogic, cfg config.AdConfig) *FavoriteHandler {
+	return &FavoriteHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type FavoriteResponse struct {
+	Ad ad.RespAd `json:"ad"`
+}
+
+func (h *FavoriteHandler) Add(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	favoriteAd, err := h.logic.AddFavorite(ctx, adID)
+	if err != nil {
+		handleFavoriteError(ctx, w, err)
+		return
+	}
+
+	result := FavoriteResponse{Ad: favoriteAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *FavoriteHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	favoriteAd, err := h.logic.RemoveFavorite(ctx, adID)
+	if err != nil {
+		handleFavoriteError(ctx, w, err)
+		return
+	}
+
+	result := FavoriteResponse{Ad: favoriteAd}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type GetFavoritesResponse struct {
+	Ads []ad.RespAd `json:"ads"`
+}
+
+func (h *FavoriteHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	favorites, err := h.logic.GetFavorites(ctx, limit, offset)
+	if err != nil {
+		handleFavoriteError(ctx, w, err)
+		return
+	}
+
+	result := GetFavoritesResponse{Ads: favorites}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleFavoriteError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, ad.ErrAdNotFound):
+		utils.LogError(ctx, err, "ad not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	default:
+		utils.LogError(ctx, err, "failed to perform operation")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
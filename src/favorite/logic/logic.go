@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/favorite"
+	"pet_adopter/src/utils"
+)
+
+type FavoriteLogic struct {
+	repo   favorite.FavoriteRepo
+	adRepo ad.AdRepo
+}
+
+func NewFavoriteLogic(repo favorite.FavoriteRepo, adRepo ad.AdRepo) FavoriteLogic {
+	return FavoriteLogic{
+		repo:   repo,
+		adRepo: adRepo,
+	}
+}
+
+func (l *FavoriteLogic) AddFavorite(ctx context.Context, adID uuid.UUID) (ad.RespAd, error) {
+	if _, err := l.getVisibleAd(ctx, adID); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	row := favorite.Favorite{
+		UserID:    utils.GetUserIDFromContext(ctx),
+		AdID:      adID,
+		CreatedAt: time.Now().Local(),
+	}
+
+	if err := l.repo.AddFavorite(ctx, row); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to add favorite")
+	}
+
+	return l.getVisibleAd(ctx, adID)
+}
+
+func (l *FavoriteLogic) RemoveFavorite(ctx context.Context, adID uuid.UUID) (ad.RespAd, error) {
+	if err := l.repo.RemoveFavorite(ctx, utils.GetUserIDFromContext(ctx), adID); err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to remove favorite")
+	}
+
+	// A favorite of an ad hidden since then is still removed, the ad itself is not shown.
+	return l.getVisibleAd(ctx, adID)
+}
+
+func (l *FavoriteLogic) GetFavorites(ctx context.Context, limit int, offset int) ([]ad.RespAd, error) {
+	adIDs, err := l.repo.GetFavoriteAdIDs(ctx, utils.GetUserIDFromContext(ctx), limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get favorite ad ids")
+	}
+
+	return l.adRepo.GetAdsByIDs(ctx, adIDs)
+}
+
+func (l *FavoriteLogic) getVisibleAd(ctx context.Context, adID uuid.UUID) (ad.RespAd, error) {
+	result, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	if !ad.IsVisible(ctx, result.Info) {
+		return ad.RespAd{}, ad.ErrAdNotFound
+	}
+
+	return result, nil
+}
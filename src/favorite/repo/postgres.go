@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/favorite"
+)
+
+const (
+	addFavorite      = `INSERT INTO Favorite(user_id, ad_id, created_at) VALUES ($1, $2, $3) ON CONFLICT (user_id, ad_id) DO NOTHING;`
+	removeFavorite   = `DELETE FROM Favorite WHERE user_id = $1 AND ad_id = $2;`
+	getFavoriteAdIDs = `SELECT ad_id FROM Favorite WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;`
+)
+
+type FavoritePostgres struct {
+	db pgxtype.Querier
+}
+
+func NewFavoritePostgres(db pgxtype.Querier) *FavoritePostgres {
+	return &FavoritePostgres{db: db}
+}
+
+func (repo *FavoritePostgres) AddFavorite(ctx context.Context, row favorite.Favorite) error {
+	if _, err := repo.db.Exec(ctx, addFavorite, row.UserID, row.AdID, row.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		return errors.Wrap(err, "failed to add favorite to postgres")
+	}
+
+	return nil
+}
+
+func (repo *FavoritePostgres) RemoveFavorite(ctx context.Context, userID uuid.UUID, adID uuid.UUID) error {
+	if _, err := repo.db.Exec(ctx, removeFavorite, userID, adID); err != nil {
+		return errors.Wrap(err, "failed to remove favorite from postgres")
+	}
+
+	return nil
+}
+
+func (repo *FavoritePostgres) GetFavoriteAdIDs(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0)
+
+	rows, err := repo.db.Query(ctx, getFavoriteAdIDs, userID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get favorites from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adID uuid.UUID
+		if err = rows.Scan(&adID); err != nil {
+			return result, errors.Wrap(err, "failed to parse favorite")
+		}
+		result = append(result, adID)
+	}
+
+	return result, nil
+}
@@ -6,15 +6,17 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
 	"pet_adopter/src/locality"
 )
 
 type LocalityLogic struct {
-	repo locality.LocalityRepo
+	repo     locality.LocalityRepo
+	auditLog audit.AuditLogic
 }
 
-func NewLocalityLogic(repo locality.LocalityRepo) LocalityLogic {
-	return LocalityLogic{repo: repo}
+func NewLocalityLogic(repo locality.LocalityRepo, auditLog audit.AuditLogic) LocalityLogic {
+	return LocalityLogic{repo: repo, auditLog: auditLog}
 }
 
 func (logic *LocalityLogic) GetLocalities(ctx context.Context) ([]locality.Locality, error) {
@@ -52,9 +54,24 @@ func (logic *LocalityLogic) GetLocalitiesByRegionID(ctx context.Context, regionI
 
 func (logic *LocalityLogic) AddLocality(ctx context.Context, name string, regionID uuid.UUID, latitude float64, longitude float64) (locality.Locality, error) {
 	result := locality.Locality{ID: uuid.NewV4(), Name: name, RegionID: regionID, Latitude: latitude, Longitude: longitude}
-	return result, logic.repo.AddLocality(ctx, result)
+	if err := logic.repo.AddLocality(ctx, result); err != nil {
+		return result, err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionCreate, audit.EntityLocality, result.ID, nil, result)
+	return result, nil
 }
 
 func (logic *LocalityLogic) RemoveLocalityByID(ctx context.Context, id uuid.UUID) error {
-	return logic.repo.RemoveLocalityByID(ctx, id)
+	before, err := logic.repo.GetLocalityByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = logic.repo.RemoveLocalityByID(ctx, id); err != nil {
+		return err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionDelete, audit.EntityLocality, id, before, nil)
+	return nil
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"pet_adopter/src/user"
+	"pet_adopter/src/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// CreatePermissionMiddleware lets through users whose role has the permission. It reads the role put into
+// the context by the session middleware, so it must be wrapped by a session middleware requiring auth.
+func CreatePermissionMiddleware(permission string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := utils.GetUserRoleFromContext(r.Context())
+			if !user.HasPermission(role, permission) {
+				utils.LogErrorMessage(r.Context(), fmt.Sprintf("role %q has no permission %s", role, permission))
+				http.Error(w, utils.Forbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -32,7 +32,8 @@ func CreateRequestIDMiddleware(logger *slog.Logger) mux.MiddlewareFunc {
 			reqID := uuid.NewV4().String()
 			reqIDLogger := logger.With(slog.String("x-request-id", reqID))
 
-			r = r.WithContext(context.WithValue(r.Context(), config.LoggerContextKey, reqIDLogger))
+			ctx := context.WithValue(r.Context(), config.LoggerContextKey, reqIDLogger)
+			r = r.WithContext(context.WithValue(ctx, config.RequestIDContextKey, reqID))
 			resp := response{ResponseWriter: w}
 			resp.Header().Set("X-Request-ID", reqID)
 			resp.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -6,44 +6,75 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"pet_adopter/src/config"
+	"pet_adopter/src/user"
 	"pet_adopter/src/user/logic"
 	"pet_adopter/src/utils"
 
 	"github.com/gorilla/mux"
 )
 
-const msgNoAuth = "no auth"
+const (
+	msgNoAuth = "no auth"
 
-func hasAuth(r *http.Request, sessionLogic *logic.SessionLogic, cfg config.SessionConfig) (int, func(), bool) {
+	// legacyUsernameParam used to identify the caller, the session of the token does it now.
+	legacyUsernameParam = "username"
+)
+
+func hasAuth(r *http.Request, sessionLogic *logic.SessionLogic, cfg config.SessionConfig) (int, func(), user.Session, bool) {
 	ctx := r.Context()
-	username := r.URL.Query().Get("username")
 
 	headerToken := r.Header.Get("Authorization")
 	if !strings.HasPrefix(headerToken, "Bearer ") {
-		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "invalid token in Authorization header") }, false
+		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "invalid token in Authorization header") }, user.Session{}, false
 	}
 	headerToken = strings.TrimPrefix(headerToken, "Bearer ")
 
 	cookieToken, err := r.Cookie(cfg.AccessTokenCookieName)
 	if err != nil {
 		if goerrors.Is(err, http.ErrNoCookie) {
-			return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "no session cookie") }, false
+			return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "no session cookie") }, user.Session{}, false
 		}
-		return http.StatusInternalServerError, func() { utils.LogError(ctx, err, "failed to get access token from cookie") }, false
+		return http.StatusInternalServerError, func() { utils.LogError(ctx, err, "failed to get access token from cookie") }, user.Session{}, false
 	}
 
 	if cookieToken.Value != headerToken {
-		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "tokens are different") }, false
+		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "tokens are different") }, user.Session{}, false
 	}
 
-	correctSession, err := sessionLogic.CheckSession(ctx, username, headerToken)
+	session, correctSession, err := sessionLogic.CheckSession(ctx, headerToken)
 	if err != nil {
-		return http.StatusInternalServerError, func() { utils.LogError(ctx, err, "failed to check session") }, false
+		return http.StatusInternalServerError, func() { utils.LogError(ctx, err, "failed to check session") }, user.Session{}, false
 	}
 	if !correctSession {
-		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "invalid session") }, false
+		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "invalid session") }, user.Session{}, false
+	}
+
+	return http.StatusOK, func() {}, session, true
+}
+
+// checkLegacyUsername lets the clients that still send the username param work until the sunset date,
+// the param has to name the owner of the session. After the sunset the param is rejected.
+func checkLegacyUsername(w http.ResponseWriter, r *http.Request, session user.Session, cfg config.SessionConfig) (int, func(), bool) {
+	ctx := r.Context()
+
+	if !r.URL.Query().Has(legacyUsernameParam) {
+		return http.StatusOK, func() {}, true
+	}
+
+	if !cfg.UsernameParamSunset.IsZero() && !time.Now().Before(cfg.UsernameParamSunset) {
+		return http.StatusBadRequest, func() { utils.LogErrorMessage(ctx, "username param is no longer supported") }, false
+	}
+
+	if r.URL.Query().Get(legacyUsernameParam) != session.Username {
+		return http.StatusUnauthorized, func() { utils.LogErrorMessage(ctx, "username param does not match session") }, false
+	}
+
+	w.Header().Set("Deprecation", "true")
+	if !cfg.UsernameParamSunset.IsZero() {
+		w.Header().Set("Sunset", cfg.UsernameParamSunset.UTC().Format(http.TimeFormat))
 	}
 
 	return http.StatusOK, func() {}, true
@@ -52,28 +83,32 @@ func hasAuth(r *http.Request, sessionLogic *logic.SessionLogic, cfg config.Sessi
 func CreateSessionMiddleware(userLogic *logic.UserLogic, sessionLogic *logic.SessionLogic, cfg config.SessionConfig, needAuth bool) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			isAuthRequired := needAuth
-
-			if r.URL.Path == "/api/v1/ads" && r.URL.Query().Get("radius") != "" {
-				isAuthRequired = true
-			}
-
-			status, logFunc, auth := hasAuth(r, sessionLogic, cfg)
+			status, logFunc, session, auth := hasAuth(r, sessionLogic, cfg)
 			if status == http.StatusInternalServerError {
 				logFunc()
 				http.Error(w, utils.Internal, status)
 				return
 			}
 
-			if !auth && isAuthRequired {
+			if !auth && needAuth {
 				logFunc()
 				http.Error(w, msgNoAuth, status)
 				return
 			}
 
 			if auth {
-				username := r.URL.Query().Get("username")
-				userData, err := userLogic.GetUserByUsername(r.Context(), username)
+				status, logFunc, ok := checkLegacyUsername(w, r, session, cfg)
+				if !ok {
+					logFunc()
+					if status == http.StatusBadRequest {
+						http.Error(w, utils.Invalid, status)
+					} else {
+						http.Error(w, msgNoAuth, status)
+					}
+					return
+				}
+
+				userData, err := userLogic.GetUserByUsername(r.Context(), session.Username)
 				if err != nil {
 					utils.LogError(r.Context(), err, "failed to get user by username")
 					http.Error(w, utils.Internal, http.StatusInternalServerError)
@@ -82,8 +117,10 @@ func CreateSessionMiddleware(userLogic *logic.UserLogic, sessionLogic *logic.Ses
 
 				r = r.WithContext(context.WithValue(r.Context(), config.UserIDContextKey, userData.ID))
 				r = r.WithContext(context.WithValue(r.Context(), config.UsernameContextKey, userData.Username))
+				r = r.WithContext(context.WithValue(r.Context(), config.UserRoleContextKey, userData.Role))
+				r = r.WithContext(context.WithValue(r.Context(), config.SessionIDContextKey, session.ID))
 
-				utils.LogInfoMessage(r.Context(), fmt.Sprintf("user %s authenticated", username))
+				utils.LogInfoMessage(r.Context(), fmt.Sprintf("user %s authenticated", userData.Username))
 			} else {
 				utils.LogInfoMessage(r.Context(), "user is not authenticated")
 			}
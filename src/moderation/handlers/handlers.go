@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+	"pet_adopter/src/moderation"
+	"pet_adopter/src/utils"
+)
+
+type ModerationHandler struct {
+	logic moderation.ModerationLogic
+	cfg   config.AdConfig
+}
+
+func NewModerationHandler(logic moderation.ModerationLogic, cfg config.AdConfig) *ModerationHandler {
+	return &ModerationHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type ReportResponse struct {
+	Report moderation.Report `json:"report"`
+}
+
+type ReportsResponse struct {
+	Reports []moderation.Report `json:"reports"`
+}
+
+type QueueResponse struct {
+	Queue []moderation.QueueItem `json:"queue"`
+}
+
+type AdResponse struct {
+	Ad ad.RespAd `json:"ad"`
+}
+
+type AdsResponse struct {
+	Ads []ad.RespAd `json:"ads"`
+}
+
+func (h *ModerationHandler) Report(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req moderation.ReportForm
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.logic.Report(ctx, adID, req)
+	if err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	result := ReportResponse{Report: created}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ModerationHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	queue, err := h.logic.GetQueue(ctx, limit, offset)
+	if err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	result := QueueResponse{Queue: queue}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ModerationHandler) GetAdReports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	reports, err := h.logic.GetAdReports(ctx, adID, limit, offset)
+	if err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	result := ReportsResponse{Reports: reports}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ModerationHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	pending, err := h.logic.GetPending(ctx, limit, offset)
+	if err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	result := AdsResponse{Ads: pending}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type ModerateRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *ModerationHandler) Hide(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, true, h.logic.Hide)
+}
+
+func (h *ModerationHandler) Block(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, true, h.logic.Block)
+}
+
+func (h *ModerationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, false, func(ctx context.Context, adID uuid.UUID, _ string) (ad.RespAd, error) {
+		return h.logic.Approve(ctx, adID)
+	})
+}
+
+func (h *ModerationHandler) Unhide(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, false, func(ctx context.Context, adID uuid.UUID, _ string) (ad.RespAd, error) {
+		return h.logic.Unhide(ctx, adID)
+	})
+}
+
+func (h *ModerationHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.logic.Dismiss(ctx, adID); err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	utils.LogInfoMessage(ctx, fmt.Sprintf("reports on ad %s dismissed by %s", adID, utils.GetUsernameFromContext(ctx)))
+}
+
+// moderate runs the action on the ad from the path, the ModerateRequest body is read only when withReason is set.
+func (h *ModerationHandler) moderate(w http.ResponseWriter, r *http.Request, withReason bool, action func(ctx context.Context, adID uuid.UUID, reason string) (ad.RespAd, error)) {
+	ctx := r.Context()
+
+	adID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid ad id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	var req ModerateRequest
+	if withReason {
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+			return
+		}
+	}
+
+	moderated, err := action(ctx, adID, req.Reason)
+	if err != nil {
+		handleModerationError(ctx, w, err)
+		return
+	}
+
+	utils.LogInfoMessage(ctx, fmt.Sprintf("ad %s moved to status %s by %s", adID, moderated.Info.Status, utils.GetUsernameFromContext(ctx)))
+
+	result := AdResponse{Ad: moderated}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleModerationError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, ad.ErrAdNotFound):
+		utils.LogError(ctx, err, "ad not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, moderation.ErrAlreadyReported),
+		goerrors.Is(err, moderation.ErrOwnAd),
+		goerrors.Is(err, moderation.ErrInvalidReport),
+		goerrors.Is(err, moderation.ErrInvalidReason),
+		goerrors.Is(err, moderation.ErrWrongAdStatus):
+		utils.LogError(ctx, err, "invalid moderation operation")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	default:
+		utils.LogError(ctx, err, "failed to perform operation")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
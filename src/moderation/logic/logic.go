@@ -0,0 +1,145 @@
+package logic
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/config"
+	"pet_adopter/src/moderation"
+	"pet_adopter/src/utils"
+)
+
+type ModerationLogic struct {
+	repo     moderation.ModerationRepo
+	adRepo   ad.AdRepo
+	auditLog audit.AuditLogic
+	cfg      config.AdConfig
+}
+
+func NewModerationLogic(repo moderation.ModerationRepo, adRepo ad.AdRepo, auditLog audit.AuditLogic, cfg config.AdConfig) ModerationLogic {
+	return ModerationLogic{
+		repo:     repo,
+		adRepo:   adRepo,
+		auditLog: auditLog,
+		cfg:      cfg,
+	}
+}
+
+func (l *ModerationLogic) Report(ctx context.Context, adID uuid.UUID, form moderation.ReportForm) (moderation.Report, error) {
+	userID := utils.GetUserIDFromContext(ctx)
+
+	currentAd, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return moderation.Report{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	// Moderated ads are invisible to the reporter, there is nothing left to report.
+	if slices.Contains(ad.ModeratedStatuses, currentAd.Info.Status) {
+		return moderation.Report{}, ad.ErrAdNotFound
+	}
+
+	if currentAd.Info.OwnerID == userID {
+		return moderation.Report{}, moderation.ErrOwnAd
+	}
+
+	form.Comment = strings.TrimSpace(form.Comment)
+	if !slices.Contains(moderation.Reasons, form.Reason) || utf8.RuneCountInString(form.Comment) > moderation.MaxCommentLength {
+		return moderation.Report{}, moderation.ErrInvalidReport
+	}
+
+	row := moderation.Report{
+		ID:         uuid.NewV4(),
+		AdID:       adID,
+		ReporterID: userID,
+		Reason:     form.Reason,
+		Comment:    form.Comment,
+		Status:     moderation.Open,
+		CreatedAt:  time.Now().Local(),
+	}
+
+	if err = l.repo.CreateReport(ctx, row); err != nil {
+		return moderation.Report{}, errors.Wrap(err, "failed to create report")
+	}
+
+	return row, nil
+}
+
+func (l *ModerationLogic) GetQueue(ctx context.Context, limit int, offset int) ([]moderation.QueueItem, error) {
+	return l.repo.GetQueue(ctx, limit, offset)
+}
+
+func (l *ModerationLogic) GetAdReports(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]moderation.Report, error) {
+	return l.repo.GetAdReports(ctx, adID, limit, offset)
+}
+
+// GetPending lists the ads waiting for pre-publication moderation, the oldest first.
+func (l *ModerationLogic) GetPending(ctx context.Context, limit int, offset int) ([]ad.RespAd, error) {
+	params := ad.NewSearchParams(l.cfg)
+	params.Statuses = []string{ad.Pending}
+	params.Sort = ad.SortOldest
+	params.Limit = limit
+	params.Offset = offset
+
+	return l.adRepo.SearchAds(ctx, params, ad.SearchExtra{})
+}
+
+// Approve publishes a pending ad that failed the automatic checks.
+func (l *ModerationLogic) Approve(ctx context.Context, adID uuid.UUID) (ad.RespAd, error) {
+	return l.setAdStatus(ctx, adID, []string{ad.Pending}, ad.Actual, nil)
+}
+
+// Hide takes an actual ad out of search until a moderator unhides or blocks it.
+func (l *ModerationLogic) Hide(ctx context.Context, adID uuid.UUID, reason string) (ad.RespAd, error) {
+	return l.setAdStatus(ctx, adID, []string{ad.Actual}, ad.Hidden, &reason)
+}
+
+func (l *ModerationLogic) Unhide(ctx context.Context, adID uuid.UUID) (ad.RespAd, error) {
+	return l.setAdStatus(ctx, adID, []string{ad.Hidden}, ad.Actual, nil)
+}
+
+// Block rejects the ad for good, the owner can not reopen or close it afterwards.
+func (l *ModerationLogic) Block(ctx context.Context, adID uuid.UUID, reason string) (ad.RespAd, error) {
+	return l.setAdStatus(ctx, adID, []string{ad.Actual, ad.Realised, ad.Cancelled, ad.Hidden, ad.Pending}, ad.Blocked, &reason)
+}
+
+// Dismiss resolves the open reports of the ad without touching it.
+func (l *ModerationLogic) Dismiss(ctx context.Context, adID uuid.UUID) error {
+	if _, err := l.adRepo.GetAd(ctx, adID); err != nil {
+		return errors.Wrap(err, "failed to get ad")
+	}
+
+	return l.repo.ResolveReports(ctx, adID, time.Now().Local())
+}
+
+func (l *ModerationLogic) setAdStatus(ctx context.Context, adID uuid.UUID, from []string, status string, reason *string) (ad.RespAd, error) {
+	if reason != nil {
+		*reason = strings.TrimSpace(*reason)
+		if *reason == "" || utf8.RuneCountInString(*reason) > moderation.MaxModerationReasonLength {
+			return ad.RespAd{}, moderation.ErrInvalidReason
+		}
+	}
+
+	before, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return ad.RespAd{}, errors.Wrap(err, "failed to get ad")
+	}
+
+	if err = l.repo.SetAdStatus(ctx, adID, from, nil, status, reason, time.Now().Local()); err != nil {
+		return ad.RespAd{}, err
+	}
+
+	result, err := l.adRepo.GetAd(ctx, adID)
+	if err != nil {
+		return ad.RespAd{}, err
+	}
+
+	l.auditLog.Record(ctx, audit.ActionStatusChange, audit.EntityAd, adID, before.Info, result.Info)
+	return result, nil
+}
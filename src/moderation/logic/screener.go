@@ -0,0 +1,181 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/chatgpt"
+	"pet_adopter/src/config"
+	"pet_adopter/src/moderation"
+)
+
+// maxScreeningSaveAttempts bounds the retries of a screening result on an ad whose photos keep changing.
+const maxScreeningSaveAttempts = 3
+
+// Screener implements ad.Screener with the checks from config.AdModerationConfig.
+type Screener struct {
+	repo        moderation.ModerationRepo
+	adRepo      ad.AdRepo
+	client      chatgpt.ChatGPTClient
+	auditLog    audit.AuditLogic
+	bannedWords map[string]struct{}
+	contacts    *regexp.Regexp
+	cfg         config.AdConfig
+}
+
+func NewScreener(repo moderation.ModerationRepo, adRepo ad.AdRepo, client chatgpt.ChatGPTClient, auditLog audit.AuditLogic, cfg config.AdConfig) (*Screener, error) {
+	contacts, err := regexp.Compile(cfg.Moderation.ContactsPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid contacts pattern")
+	}
+
+	bannedWords := make(map[string]struct{}, len(cfg.Moderation.BannedWords))
+	for _, word := range cfg.Moderation.BannedWords {
+		bannedWords[strings.ToLower(word)] = struct{}{}
+	}
+
+	return &Screener{
+		repo:        repo,
+		adRepo:      adRepo,
+		client:      client,
+		auditLog:    auditLog,
+		bannedWords: bannedWords,
+		contacts:    contacts,
+		cfg:         cfg,
+	}, nil
+}
+
+// ScreenAd publishes the pending ad when every check passes, otherwise the ad stays pending with the failed
+// checks as its moderation reason. The result is saved only for the screened version of the ad, one edited
+// again in the meantime is left to the newer screening.
+// The audit entry is attributed to the user whose request started the screening.
+func (s *Screener) ScreenAd(ctx context.Context, id uuid.UUID) error {
+	current, err := s.adRepo.GetAd(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to get ad")
+	}
+
+	if current.Info.Status != ad.Pending {
+		return nil
+	}
+	form := current.Info.AdForm
+
+	problems := s.checkRules(form)
+	if len(problems) == 0 && s.cfg.Moderation.UseLLM {
+		verdict, err := s.askLLM(form)
+		if err != nil {
+			return errors.Wrap(err, "failed to check ad with LLM")
+		}
+		if !verdict.OK {
+			problems = append(problems, fmt.Sprintf("automatic review: %s", verdict.Reason))
+		}
+	}
+
+	var reason *string
+	status := ad.Actual
+	if len(problems) > 0 {
+		joined := strings.Join(problems, "; ")
+		if runes := []rune(joined); len(runes) > moderation.MaxModerationReasonLength {
+			joined = string(runes[:moderation.MaxModerationReasonLength])
+		}
+		reason, status = &joined, ad.Pending
+	}
+
+	// Edits that do not touch the checked fields, e.g. of the photos, change updated_at too. The result
+	// still holds for them, so it is saved for the latest version unless its content differs.
+	screened := current.Info
+	for attempt := 0; ; attempt++ {
+		err = s.repo.SetAdStatus(ctx, id, []string{ad.Pending}, &screened.UpdatedAt, status, reason, time.Now().Local())
+		if !goerrors.Is(err, moderation.ErrWrongAdStatus) || attempt == maxScreeningSaveAttempts-1 {
+			break
+		}
+
+		latest, err := s.adRepo.GetAd(ctx, id)
+		if err != nil {
+			return errors.Wrap(err, "failed to get ad")
+		}
+		if latest.Info.Status != ad.Pending || !sameScreenedContent(latest.Info.AdForm, form) {
+			return nil
+		}
+		screened = latest.Info
+	}
+
+	if err != nil {
+		if goerrors.Is(err, moderation.ErrWrongAdStatus) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to save screening result")
+	}
+
+	result, err := s.adRepo.GetAd(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to get screened ad")
+	}
+
+	s.auditLog.Record(ctx, audit.ActionScreen, audit.EntityAd, id, screened, result.Info)
+	return nil
+}
+
+// sameScreenedContent tells whether two versions of an ad are equal in the fields the checks read.
+func sameScreenedContent(a ad.AdForm, b ad.AdForm) bool {
+	return a.Title == b.Title && a.Description == b.Description && a.Contacts == b.Contacts && a.Price == b.Price
+}
+
+func (s *Screener) checkRules(form ad.AdForm) []string {
+	problems := make([]string, 0)
+
+	if form.Price < 0 || form.Price > s.cfg.MaxPrice {
+		problems = append(problems, fmt.Sprintf("price must be between 0 and %d", s.cfg.MaxPrice))
+	}
+
+	if !s.contacts.MatchString(strings.TrimSpace(form.Contacts)) {
+		problems = append(problems, "contacts must be a phone number, an email or a messenger username")
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(form.Title+" "+form.Description+" "+form.Contacts), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if _, banned := s.bannedWords[word]; banned {
+			problems = append(problems, fmt.Sprintf("banned word: %s", word))
+			break
+		}
+	}
+
+	return problems
+}
+
+func (s *Screener) askLLM(form ad.AdForm) (chatgpt.Verdict, error) {
+	content := chatgpt.Content{
+		{
+			Type: chatgpt.ContentTypeText,
+			Text: chatgpt.ScreenAdPrompt + form.Title + "\n" + form.Description + "\n" + form.Contacts,
+		},
+	}
+
+	answer, err := s.client.SendRequest(content)
+	if err != nil {
+		return chatgpt.Verdict{}, errors.Wrap(err, "SendRequest failed")
+	}
+
+	answer = strings.TrimPrefix(answer, "```")
+	answer = strings.TrimPrefix(answer, "json")
+	answer = strings.TrimSuffix(answer, "```")
+
+	var verdict chatgpt.Verdict
+	if err = json.Unmarshal([]byte(answer), &verdict); err != nil {
+		return chatgpt.Verdict{}, errors.Wrap(err, "failed to unmarshal answer into verdict")
+	}
+
+	return verdict, nil
+}
@@ -0,0 +1,81 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+var (
+	ErrAlreadyReported = errors.New("already reported")
+	ErrOwnAd           = errors.New("can not report own ad")
+	ErrInvalidReport   = errors.New("invalid report")
+	ErrInvalidReason   = errors.New("invalid moderation reason")
+	ErrWrongAdStatus   = errors.New("ad status does not allow the action")
+)
+
+const (
+	Open     = "O"
+	Resolved = "R"
+
+	ReasonScam          = "scam"
+	ReasonInappropriate = "inappropriate"
+	ReasonSpam          = "spam"
+	ReasonOther         = "other"
+
+	MaxCommentLength          = 1024
+	MaxModerationReasonLength = 1024
+)
+
+var Reasons = []string{ReasonScam, ReasonInappropriate, ReasonSpam, ReasonOther}
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	AdID       uuid.UUID  `json:"ad_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Comment    string     `json:"comment"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type ReportForm struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+// QueueItem groups the open reports of one ad.
+type QueueItem struct {
+	AdID            uuid.UUID `json:"ad_id"`
+	AdTitle         string    `json:"ad_title"`
+	AdStatus        string    `json:"ad_status"`
+	OwnerUsername   string    `json:"owner_username"`
+	ReportsCount    int       `json:"reports_count"`
+	Reasons         []string  `json:"reasons"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+	LastReportedAt  time.Time `json:"last_reported_at"`
+}
+
+type ModerationRepo interface {
+	CreateReport(ctx context.Context, report Report) error
+	GetQueue(ctx context.Context, limit int, offset int) ([]QueueItem, error)
+	GetAdReports(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]Report, error)
+	SetAdStatus(ctx context.Context, adID uuid.UUID, from []string, updatedAt *time.Time, status string, reason *string, now time.Time) error
+	ResolveReports(ctx context.Context, adID uuid.UUID, now time.Time) error
+}
+
+type ModerationLogic interface {
+	Report(ctx context.Context, adID uuid.UUID, form ReportForm) (Report, error)
+	GetQueue(ctx context.Context, limit int, offset int) ([]QueueItem, error)
+	GetAdReports(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]Report, error)
+	GetPending(ctx context.Context, limit int, offset int) ([]ad.RespAd, error)
+	Approve(ctx context.Context, adID uuid.UUID) (ad.RespAd, error)
+	Hide(ctx context.Context, adID uuid.UUID, reason string) (ad.RespAd, error)
+	Unhide(ctx context.Context, adID uuid.UUID) (ad.RespAd, error)
+	Block(ctx context.Context, adID uuid.UUID, reason string) (ad.RespAd, error)
+	Dismiss(ctx context.Context, adID uuid.UUID) error
+}
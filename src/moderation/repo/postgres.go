@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/moderation"
+)
+
+const (
+	createReport = `INSERT INTO AdReport(id, ad_id, reporter_id, reason, comment, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+	getQueue     = `
+SELECT
+	Ad.id, Ad.title, Ad.status, MyUser.username,
+	COUNT(*), array_agg(DISTINCT AdReport.reason), MIN(AdReport.created_at), MAX(AdReport.created_at)
+FROM AdReport
+JOIN Ad ON AdReport.ad_id = Ad.id
+JOIN MyUser ON Ad.owner_id = MyUser.id
+WHERE AdReport.status = 'O'
+GROUP BY Ad.id, MyUser.username
+ORDER BY COUNT(*) DESC, MIN(AdReport.created_at)
+LIMIT $1 OFFSET $2;
+`
+	getAdReports = `
+SELECT id, ad_id, reporter_id, reason, comment, status, created_at, resolved_at
+FROM AdReport
+WHERE ad_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+`
+	// setAdStatus moves the ad only from the allowed statuses and, when $6 is set, only the version updated at $6.
+	// It resolves the open reports of the ad in the same statement.
+	setAdStatus = `
+WITH moderated AS (
+	UPDATE Ad SET status = $2, moderation_reason = $3, updated_at = $4,
+		published_at = CASE WHEN status <> 'A' AND $2 = 'A' THEN $4 ELSE published_at END
+	WHERE id = $1 AND status::text = ANY($5::text[]) AND ($6::timestamptz IS NULL OR updated_at = $6)
+	RETURNING id
+), resolved AS (
+	UPDATE AdReport SET status = 'R', resolved_at = $4
+	WHERE ad_id = (SELECT id FROM moderated) AND status = 'O'
+)
+SELECT COUNT(*) FROM moderated;
+`
+	resolveReports = `UPDATE AdReport SET status = 'R', resolved_at = $2 WHERE ad_id = $1 AND status = 'O';`
+)
+
+type ModerationPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewModerationPostgres(db pgxtype.Querier) *ModerationPostgres {
+	return &ModerationPostgres{db: db}
+}
+
+func (repo *ModerationPostgres) CreateReport(ctx context.Context, row moderation.Report) error {
+	if _, err := repo.db.Exec(ctx, createReport, row.ID, row.AdID, row.ReporterID, row.Reason, row.Comment, row.Status, row.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		if strings.Contains(err.Error(), "violates unique constraint") {
+			return moderation.ErrAlreadyReported
+		}
+		return errors.Wrap(err, "failed to create report in postgres")
+	}
+
+	return nil
+}
+
+func (repo *ModerationPostgres) GetQueue(ctx context.Context, limit int, offset int) ([]moderation.QueueItem, error) {
+	result := make([]moderation.QueueItem, 0)
+
+	rows, err := repo.db.Query(ctx, getQueue, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get moderation queue from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row moderation.QueueItem
+		if err = rows.Scan(
+			&row.AdID, &row.AdTitle, &row.AdStatus, &row.OwnerUsername,
+			&row.ReportsCount, &row.Reasons, &row.FirstReportedAt, &row.LastReportedAt,
+		); err != nil {
+			return result, errors.Wrap(err, "failed to parse moderation queue item")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repo *ModerationPostgres) GetAdReports(ctx context.Context, adID uuid.UUID, limit int, offset int) ([]moderation.Report, error) {
+	result := make([]moderation.Report, 0)
+
+	rows, err := repo.db.Query(ctx, getAdReports, adID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get reports from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row moderation.Report
+		if err = rows.Scan(&row.ID, &row.AdID, &row.ReporterID, &row.Reason, &row.Comment, &row.Status, &row.CreatedAt, &row.ResolvedAt); err != nil {
+			return result, errors.Wrap(err, "failed to parse report")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repo *ModerationPostgres) SetAdStatus(ctx context.Context, adID uuid.UUID, from []string, updatedAt *time.Time, status string, reason *string, now time.Time) error {
+	var moderated int
+	if err := repo.db.QueryRow(ctx, setAdStatus, adID, status, reason, now, from, updatedAt).Scan(&moderated); err != nil {
+		return errors.Wrap(err, "failed to set ad status in postgres")
+	}
+
+	if moderated == 0 {
+		return moderation.ErrWrongAdStatus
+	}
+
+	return nil
+}
+
+func (repo *ModerationPostgres) ResolveReports(ctx context.Context, adID uuid.UUID, now time.Time) error {
+	if _, err := repo.db.Exec(ctx, resolveReports, adID, now); err != nil {
+		return errors.Wrap(err, "failed to resolve reports in postgres")
+	}
+
+	return nil
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 
 	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
 	"pet_adopter/src/region"
 )
 
 type RegionLogic struct {
-	repo region.RegionRepo
+	repo     region.RegionRepo
+	auditLog audit.AuditLogic
 }
 
-func NewRegionLogic(repo region.RegionRepo) RegionLogic {
-	return RegionLogic{repo: repo}
+func NewRegionLogic(repo region.RegionRepo, auditLog audit.AuditLogic) RegionLogic {
+	return RegionLogic{repo: repo, auditLog: auditLog}
 }
 
 func (logic *RegionLogic) GetRegions(ctx context.Context) ([]region.Region, error) {
@@ -25,9 +27,24 @@ func (logic *RegionLogic) GetRegionByID(ctx context.Context, id uuid.UUID) (regi
 
 func (logic *RegionLogic) AddRegion(ctx context.Context, name string) (region.Region, error) {
 	result := region.Region{ID: uuid.NewV4(), Name: name}
-	return result, logic.repo.AddRegion(ctx, result)
+	if err := logic.repo.AddRegion(ctx, result); err != nil {
+		return result, err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionCreate, audit.EntityRegion, result.ID, nil, result)
+	return result, nil
 }
 
 func (logic *RegionLogic) RemoveRegionByID(ctx context.Context, id uuid.UUID) error {
-	return logic.repo.RemoveRegionByID(ctx, id)
+	before, err := logic.repo.GetRegionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = logic.repo.RemoveRegionByID(ctx, id); err != nil {
+		return err
+	}
+
+	logic.auditLog.Record(ctx, audit.ActionDelete, audit.EntityRegion, id, before, nil)
+	return nil
 }
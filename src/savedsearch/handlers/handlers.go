@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/satori/uuid"
+	"pet_adopter/src/config"
+	"pet_adopter/src/savedsearch"
+	"pet_adopter/src/utils"
+)
+
+type SavedSearchHandler struct {
+	logic savedsearch.SavedSearchLogic
+	cfg   config.AdConfig
+}
+
+func NewSavedSearchHandler(logic savedsearch.SavedSearchLogic, cfg config.AdConfig) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type CreateResponse struct {
+	SavedSearch savedsearch.SavedSearch `json:"saved_search"`
+}
+
+func (h *SavedSearchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req savedsearch.SavedSearchForm
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.logic.CreateSavedSearch(ctx, req)
+	if err != nil {
+		handleSavedSearchError(ctx, w, err)
+		return
+	}
+
+	result := CreateResponse{SavedSearch: created}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type GetSavedSearchesResponse struct {
+	SavedSearches []savedsearch.SavedSearch `json:"saved_searches"`
+}
+
+func (h *SavedSearchHandler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	searches, err := h.logic.GetSavedSearches(ctx)
+	if err != nil {
+		handleSavedSearchError(ctx, w, err)
+		return
+	}
+
+	result := GetSavedSearchesResponse{SavedSearches: searches}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *SavedSearchHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	searchID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid saved search id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.logic.RemoveSavedSearch(ctx, searchID); err != nil {
+		handleSavedSearchError(ctx, w, err)
+		return
+	}
+}
+
+type GetNotificationsResponse struct {
+	Notifications []savedsearch.RespNotification `json:"notifications"`
+}
+
+func (h *SavedSearchHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := h.logic.GetNotifications(ctx, limit, offset)
+	if err != nil {
+		handleSavedSearchError(ctx, w, err)
+		return
+	}
+
+	result := GetNotificationsResponse{Notifications: notifications}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleSavedSearchError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case goerrors.Is(err, savedsearch.ErrSavedSearchNotFound):
+		utils.LogError(ctx, err, "saved search not found")
+		http.Error(w, utils.NotFound, http.StatusNotFound)
+	case goerrors.Is(err, savedsearch.ErrTooManySavedSearches), goerrors.Is(err, savedsearch.ErrInvalidSavedSearch):
+		utils.LogError(ctx, err, "invalid saved search")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+	default:
+		utils.LogError(ctx, err, "failed to perform operation")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,255 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+	"pet_adopter/src/locality"
+	"pet_adopter/src/savedsearch"
+	"pet_adopter/src/user"
+	"pet_adopter/src/utils"
+)
+
+type SavedSearchLogic struct {
+	repo         savedsearch.SavedSearchRepo
+	adRepo       ad.AdRepo
+	userRepo     user.UserRepo
+	localityRepo locality.LocalityRepo
+	cfg          config.SavedSearchConfig
+	adCfg        config.AdConfig
+}
+
+func NewSavedSearchLogic(repo savedsearch.SavedSearchRepo, adRepo ad.AdRepo, userRepo user.UserRepo, localityRepo locality.LocalityRepo, cfg config.SavedSearchConfig, adCfg config.AdConfig) SavedSearchLogic {
+	return SavedSearchLogic{
+		repo:         repo,
+		adRepo:       adRepo,
+		userRepo:     userRepo,
+		localityRepo: localityRepo,
+		cfg:          cfg,
+		adCfg:        adCfg,
+	}
+}
+
+func (l *SavedSearchLogic) CreateSavedSearch(ctx context.Context, form savedsearch.SavedSearchForm) (savedsearch.SavedSearch, error) {
+	now := time.Now().Local()
+	userID := utils.GetUserIDFromContext(ctx)
+
+	form.Name = strings.TrimSpace(form.Name)
+	if err := l.validateForm(form); err != nil {
+		return savedsearch.SavedSearch{}, err
+	}
+
+	count, err := l.repo.CountSavedSearches(ctx, userID)
+	if err != nil {
+		return savedsearch.SavedSearch{}, errors.Wrap(err, "failed to count saved searches")
+	}
+
+	if count >= l.cfg.MaxPerUser {
+		return savedsearch.SavedSearch{}, savedsearch.ErrTooManySavedSearches
+	}
+
+	// Only the filters are stored, the page and the check window are chosen on every run.
+	params := form.Params
+	params.AllStatuses = false
+	params.PublishedAfter = nil
+	params.Limit = 0
+	params.Offset = 0
+
+	row := savedsearch.SavedSearch{
+		ID:        uuid.NewV4(),
+		UserID:    userID,
+		Name:      form.Name,
+		Params:    params,
+		CheckedAt: now,
+		CreatedAt: now,
+	}
+
+	if err = l.repo.CreateSavedSearch(ctx, row); err != nil {
+		return savedsearch.SavedSearch{}, errors.Wrap(err, "failed to create saved search")
+	}
+
+	return row, nil
+}
+
+func (l *SavedSearchLogic) GetSavedSearches(ctx context.Context) ([]savedsearch.SavedSearch, error) {
+	return l.repo.GetSavedSearches(ctx, utils.GetUserIDFromContext(ctx))
+}
+
+func (l *SavedSearchLogic) RemoveSavedSearch(ctx context.Context, id uuid.UUID) error {
+	return l.repo.RemoveSavedSearch(ctx, id, utils.GetUserIDFromContext(ctx))
+}
+
+func (l *SavedSearchLogic) GetNotifications(ctx context.Context, limit int, offset int) ([]savedsearch.RespNotification, error) {
+	userID := utils.GetUserIDFromContext(ctx)
+
+	notifications, err := l.repo.GetNotifications(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get notifications")
+	}
+
+	unread := make([]uuid.UUID, 0, len(notifications))
+	for _, notification := range notifications {
+		if notification.Info.ReadAt == nil {
+			unread = append(unread, notification.Info.ID)
+		}
+	}
+
+	if len(unread) > 0 {
+		if err = l.repo.MarkNotificationsRead(ctx, userID, unread, time.Now().Local()); err != nil {
+			return nil, errors.Wrap(err, "failed to mark notifications as read")
+		}
+	}
+
+	return notifications, nil
+}
+
+// Run checks saved searches every CheckInterval until ctx is cancelled.
+func (l *SavedSearchLogic) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.CheckNewAds(ctx); err != nil {
+				utils.LogError(ctx, err, "failed to check saved searches")
+			}
+		}
+	}
+}
+
+func (l *SavedSearchLogic) CheckNewAds(ctx context.Context) error {
+	searches, err := l.repo.GetAllSavedSearches(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get saved searches")
+	}
+
+	for _, search := range searches {
+		if err = l.checkSavedSearch(ctx, search); err != nil {
+			utils.LogError(ctx, err, fmt.Sprintf("failed to check saved search, id = %s", search.ID.String()))
+		}
+	}
+
+	return nil
+}
+
+func (l *SavedSearchLogic) checkSavedSearch(ctx context.Context, search savedsearch.SavedSearch) error {
+	now := time.Now().Local()
+	ctx = context.WithValue(ctx, config.UserIDContextKey, search.UserID)
+
+	// The window overlaps the previous one so ads committed late are not missed, duplicates are skipped by the repo.
+	// It is matched against the publication, an ad approved by a moderator long after its creation is still new.
+	publishedAfter := search.CheckedAt.Add(-l.cfg.CheckOverlap)
+
+	params := search.Params
+	params.AllStatuses = false
+	params.PublishedAfter = &publishedAfter
+	params.Sort = ad.SortNewest
+	params.Limit = l.cfg.BatchSize
+
+	extra := ad.SearchExtra{}
+	if params.Radius != nil {
+		loc, err := l.getSearchCenter(ctx, params, search.UserID)
+		if err != nil {
+			utils.LogError(ctx, err, "failed to get search centre")
+			params.Radius = nil
+		} else {
+			extra.HasLocation = true
+			extra.Latitude = loc.Latitude
+			extra.Longitude = loc.Longitude
+		}
+	}
+
+	for params.Offset = 0; ; params.Offset += params.Limit {
+		found, err := l.adRepo.SearchAds(ctx, params, extra)
+		if err != nil {
+			return errors.Wrap(err, "failed to search ads")
+		}
+
+		notifications := make([]savedsearch.Notification, 0, len(found))
+		for _, foundAd := range found {
+			if foundAd.Info.OwnerID == search.UserID {
+				continue
+			}
+
+			notifications = append(notifications, savedsearch.Notification{
+				ID:            uuid.NewV4(),
+				UserID:        search.UserID,
+				Kind:          savedsearch.SearchMatch,
+				SavedSearchID: &search.ID,
+				AdID:          foundAd.Info.ID,
+				CreatedAt:     now,
+			})
+		}
+
+		if err = l.repo.AddNotifications(ctx, notifications); err != nil {
+			return errors.Wrap(err, "failed to add notifications")
+		}
+
+		if len(found) < params.Limit {
+			break
+		}
+	}
+
+	return l.repo.SetCheckedAt(ctx, search.ID, now)
+}
+
+// getSearchCenter returns the centre saved with the search or the user's current locality.
+func (l *SavedSearchLogic) getSearchCenter(ctx context.Context, params ad.SearchParams, userID uuid.UUID) (locality.Locality, error) {
+	if params.Latitude != nil && params.Longitude != nil {
+		return locality.Locality{Latitude: *params.Latitude, Longitude: *params.Longitude}, nil
+	}
+
+	if params.LocalityID != nil {
+		return l.localityRepo.GetLocalityByID(ctx, *params.LocalityID)
+	}
+
+	userInfo, err := l.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return locality.Locality{}, errors.Wrap(err, "failed to get user")
+	}
+
+	return l.localityRepo.GetLocalityByID(ctx, userInfo.LocalityID)
+}
+
+func (l *SavedSearchLogic) validateForm(form savedsearch.SavedSearchForm) error {
+	if form.Name == "" || utf8.RuneCountInString(form.Name) > savedsearch.MaxNameLength {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	params := form.Params
+	if params.MinPrice != nil && (*params.MinPrice < 0 || *params.MinPrice > l.adCfg.MaxPrice) {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	if params.MaxPrice != nil && (*params.MaxPrice < 0 || *params.MaxPrice > l.adCfg.MaxPrice) {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	if params.Radius != nil && *params.Radius <= 0 {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	if (params.Latitude == nil) != (params.Longitude == nil) {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	if params.Latitude != nil && (params.LocalityID != nil || !utils.ValidCoordinates(*params.Latitude, *params.Longitude)) {
+		return savedsearch.ErrInvalidSavedSearch
+	}
+
+	return nil
+}
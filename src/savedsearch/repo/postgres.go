@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/savedsearch"
+)
+
+const (
+	createSavedSearch   = `INSERT INTO SavedSearch(id, user_id, name, params, checked_at, created_at) VALUES ($1, $2, $3, $4, $5, $6);`
+	countSavedSearches  = `SELECT COUNT(*) FROM SavedSearch WHERE user_id = $1;`
+	getSavedSearches    = `SELECT id, user_id, name, params, checked_at, created_at FROM SavedSearch WHERE user_id = $1 ORDER BY created_at DESC;`
+	getAllSavedSearches = `SELECT id, user_id, name, params, checked_at, created_at FROM SavedSearch ORDER BY checked_at;`
+	removeSavedSearch   = `DELETE FROM SavedSearch WHERE id = $1 AND user_id = $2;`
+	setCheckedAt        = `UPDATE SavedSearch SET checked_at = $2 WHERE id = $1;`
+	addNotification     = `
+INSERT INTO SearchNotification(id, user_id, kind, saved_search_id, ad_id, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (saved_search_id, ad_id) DO NOTHING;
+`
+	getNotifications = `
+SELECT
+	SearchNotification.id, SearchNotification.user_id, SearchNotification.kind,
+	SearchNotification.saved_search_id, SearchNotification.application_id, SearchNotification.ad_id,
+	SearchNotification.read_at, SearchNotification.created_at,
+	COALESCE(SavedSearch.name, ''),
+	Ad.title
+FROM SearchNotification
+LEFT JOIN SavedSearch ON SearchNotification.saved_search_id = SavedSearch.id
+JOIN Ad ON SearchNotification.ad_id = Ad.id
+WHERE SearchNotification.user_id = $1
+ORDER BY SearchNotification.created_at DESC
+LIMIT $2 OFFSET $3;
+`
+	markNotificationsRead = `UPDATE SearchNotification SET read_at = $2 WHERE user_id = $1 AND id = ANY($3::uuid[]) AND read_at IS NULL;`
+)
+
+type SavedSearchPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewSavedSearchPostgres(db pgxtype.Querier) *SavedSearchPostgres {
+	return &SavedSearchPostgres{db: db}
+}
+
+func (repo *SavedSearchPostgres) CreateSavedSearch(ctx context.Context, search savedsearch.SavedSearch) error {
+	params, err := json.Marshal(search.Params)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal search params")
+	}
+
+	if _, err = repo.db.Exec(ctx, createSavedSearch, search.ID, search.UserID, search.Name, params, search.CheckedAt, search.CreatedAt); err != nil {
+		return errors.Wrap(err, "failed to create saved search in postgres")
+	}
+
+	return nil
+}
+
+func (repo *SavedSearchPostgres) CountSavedSearches(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	if err := repo.db.QueryRow(ctx, countSavedSearches, userID).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "failed to count saved searches in postgres")
+	}
+
+	return count, nil
+}
+
+func (repo *SavedSearchPostgres) GetSavedSearches(ctx context.Context, userID uuid.UUID) ([]savedsearch.SavedSearch, error) {
+	return repo.getSavedSearches(ctx, getSavedSearches, userID)
+}
+
+func (repo *SavedSearchPostgres) GetAllSavedSearches(ctx context.Context) ([]savedsearch.SavedSearch, error) {
+	return repo.getSavedSearches(ctx, getAllSavedSearches)
+}
+
+func (repo *SavedSearchPostgres) RemoveSavedSearch(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	tag, err := repo.db.Exec(ctx, removeSavedSearch, id, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove saved search from postgres")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return savedsearch.ErrSavedSearchNotFound
+	}
+
+	return nil
+}
+
+func (repo *SavedSearchPostgres) SetCheckedAt(ctx context.Context, id uuid.UUID, checkedAt time.Time) error {
+	if _, err := repo.db.Exec(ctx, setCheckedAt, id, checkedAt); err != nil {
+		return errors.Wrap(err, "failed to set saved search checked_at in postgres")
+	}
+
+	return nil
+}
+
+func (repo *SavedSearchPostgres) AddNotifications(ctx context.Context, notifications []savedsearch.Notification) error {
+	for _, row := range notifications {
+		if _, err := repo.db.Exec(ctx, addNotification, row.ID, row.UserID, row.Kind, row.SavedSearchID, row.AdID, row.CreatedAt); err != nil {
+			return errors.Wrap(err, "failed to add notification to postgres")
+		}
+	}
+
+	return nil
+}
+
+func (repo *SavedSearchPostgres) GetNotifications(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]savedsearch.RespNotification, error) {
+	result := make([]savedsearch.RespNotification, 0)
+
+	rows, err := repo.db.Query(ctx, getNotifications, userID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get notifications from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row           savedsearch.RespNotification
+			savedSearchID []byte
+			applicationID []byte
+		)
+		if err = rows.Scan(
+			&row.Info.ID, &row.Info.UserID, &row.Info.Kind, &savedSearchID, &applicationID, &row.Info.AdID, &row.Info.ReadAt, &row.Info.CreatedAt,
+			&row.ExtraInfo.SavedSearchName, &row.ExtraInfo.AdTitle,
+		); err != nil {
+			return result, errors.Wrap(err, "failed to parse notification")
+		}
+
+		if id := uuid.FromBytesOrNil(savedSearchID); id != uuid.Nil {
+			row.Info.SavedSearchID = &id
+		}
+		if id := uuid.FromBytesOrNil(applicationID); id != uuid.Nil {
+			row.Info.ApplicationID = &id
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// MarkNotificationsRead marks only the given notifications, the ones on the pages not fetched yet stay unread.
+func (repo *SavedSearchPostgres) MarkNotificationsRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, now time.Time) error {
+	idStrings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrings = append(idStrings, id.String())
+	}
+
+	if _, err := repo.db.Exec(ctx, markNotificationsRead, userID, now, idStrings); err != nil {
+		return errors.Wrap(err, "failed to mark notifications as read in postgres")
+	}
+
+	return nil
+}
+
+func (repo *SavedSearchPostgres) getSavedSearches(ctx context.Context, query string, args ...interface{}) ([]savedsearch.SavedSearch, error) {
+	result := make([]savedsearch.SavedSearch, 0)
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get saved searches from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := scanSavedSearch(rows)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to parse saved search")
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func scanSavedSearch(row pgx.Row) (savedsearch.SavedSearch, error) {
+	var (
+		result savedsearch.SavedSearch
+		params []byte
+	)
+
+	if err := row.Scan(&result.ID, &result.UserID, &result.Name, &params, &result.CheckedAt, &result.CreatedAt); err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal(params, &result.Params); err != nil {
+		return result, errors.Wrap(err, "failed to unmarshal search params")
+	}
+
+	return result, nil
+}
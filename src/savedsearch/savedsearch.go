@@ -0,0 +1,80 @@
+package savedsearch
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+var (
+	ErrSavedSearchNotFound  = errors.New("saved search not found")
+	ErrTooManySavedSearches = errors.New("too many saved searches")
+	ErrInvalidSavedSearch   = errors.New("invalid saved search")
+)
+
+const (
+	MaxNameLength = 64
+
+	// SearchMatch notifications point to a new ad matching a saved search, AdoptedByAnother ones to
+	// an application rejected automatically because another applicant got the pet.
+	SearchMatch      = "S"
+	AdoptedByAnother = "A"
+)
+
+type SavedSearch struct {
+	ID        uuid.UUID       `json:"id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Name      string          `json:"name"`
+	Params    ad.SearchParams `json:"params"`
+	CheckedAt time.Time       `json:"checked_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type SavedSearchForm struct {
+	Name   string          `json:"name"`
+	Params ad.SearchParams `json:"params"`
+}
+
+type Notification struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Kind          string     `json:"kind"`
+	SavedSearchID *uuid.UUID `json:"saved_search_id,omitempty"`
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"`
+	AdID          uuid.UUID  `json:"ad_id"`
+	ReadAt        *time.Time `json:"read_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type NotificationInfo struct {
+	SavedSearchName string `json:"saved_search_name"`
+	AdTitle         string `json:"ad_title"`
+}
+
+type RespNotification struct {
+	Info      Notification     `json:"info"`
+	ExtraInfo NotificationInfo `json:"extra_info"`
+}
+
+type SavedSearchRepo interface {
+	CreateSavedSearch(ctx context.Context, search SavedSearch) error
+	CountSavedSearches(ctx context.Context, userID uuid.UUID) (int, error)
+	GetSavedSearches(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error)
+	GetAllSavedSearches(ctx context.Context) ([]SavedSearch, error)
+	RemoveSavedSearch(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	SetCheckedAt(ctx context.Context, id uuid.UUID, checkedAt time.Time) error
+	AddNotifications(ctx context.Context, notifications []Notification) error
+	GetNotifications(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]RespNotification, error)
+	MarkNotificationsRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, now time.Time) error
+}
+
+type SavedSearchLogic interface {
+	CreateSavedSearch(ctx context.Context, form SavedSearchForm) (SavedSearch, error)
+	GetSavedSearches(ctx context.Context) ([]SavedSearch, error)
+	RemoveSavedSearch(ctx context.Context, id uuid.UUID) error
+	GetNotifications(ctx context.Context, limit int, offset int) ([]RespNotification, error)
+	CheckNewAds(ctx context.Context) error
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	goerrors "errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/satori/uuid"
 	"pet_adopter/src/config"
 	"pet_adopter/src/locality"
@@ -93,23 +95,14 @@ func (h *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.session.SetSession(r.Context(), userData.Username)
+	accessToken, refreshToken, err := h.session.SetSession(r.Context(), userData.Username, getDevice(r))
 	if err != nil {
 		utils.LogError(r.Context(), err, "failed to set session")
 		http.Error(w, utils.Internal, http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     h.sessionCfg.AccessTokenCookieName,
-		Secure:   h.sessionCfg.ProtectedCookies,
-		Value:    accessToken,
-		HttpOnly: true,
-		Expires:  time.Now().Local().Add(h.sessionCfg.AccessTokenLifeTime),
-		Path:     "/",
-		SameSite: http.SameSiteLaxMode,
-	})
-	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	h.setAccessToken(w, accessToken)
 
 	resp := SignUpResponse{
 		User:         userData,
@@ -179,23 +172,14 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.session.SetSession(ctx, userData.Username)
+	accessToken, refreshToken, err := h.session.SetSession(ctx, userData.Username, getDevice(r))
 	if err != nil {
 		utils.LogError(ctx, err, "failed to set session")
 		http.Error(w, utils.Internal, http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-	http.SetCookie(w, &http.Cookie{
-		Name:     h.sessionCfg.AccessTokenCookieName,
-		Secure:   h.sessionCfg.ProtectedCookies,
-		Value:    accessToken,
-		HttpOnly: true,
-		Expires:  time.Now().Local().Add(h.sessionCfg.AccessTokenLifeTime),
-		Path:     "/",
-		SameSite: http.SameSiteLaxMode,
-	})
+	h.setAccessToken(w, accessToken)
 
 	resp := LoginResponse{
 		User:         userData,
@@ -221,6 +205,65 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshResponse struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh
+// @Summary	Refresh
+// @Description	Exchange the refresh token for a new access and refresh token, a reused refresh token revokes the session
+// @Tags user
+// @ID refresh
+// @Accept json
+// @Produce	json
+// @Param token body RefreshRequest true "request"
+// @Success	200	{object} RefreshResponse "response 200"
+// @Failure	400	{object} string "response 400" "invalid"
+// @Failure	401	{object} string "response 401" "no auth"
+// @Failure	500	{object} string "response 500" "internal"
+// @Router /user/refresh [post]
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := RefreshRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		utils.LogErrorMessage(ctx, "empty refresh token")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	accessToken, refreshToken, err := h.session.RefreshSession(ctx, req.RefreshToken)
+	if err != nil {
+		if goerrors.Is(err, user.ErrInvalidRefreshToken) || goerrors.Is(err, user.ErrRefreshTokenReused) {
+			utils.LogError(ctx, err, "failed to refresh session")
+			http.Error(w, "no auth", http.StatusUnauthorized)
+		} else {
+			utils.LogError(ctx, err, "failed to refresh session")
+			http.Error(w, utils.Internal, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.setAccessToken(w, accessToken)
+
+	resp := RefreshResponse{RefreshToken: refreshToken}
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
 // Logout
 // @Summary	Logout
 // @Description	logout
@@ -232,6 +275,15 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Failure	401
 // @Router /user/logout [post]
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.session.RevokeSession(ctx, utils.GetUsernameFromContext(ctx), utils.GetSessionIDFromContext(ctx))
+	if err != nil && !goerrors.Is(err, user.ErrSessionNotFound) {
+		utils.LogError(ctx, err, "failed to revoke session")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Del("Authorization")
 	http.SetCookie(w, &http.Cookie{
 		Name:     h.sessionCfg.AccessTokenCookieName,
@@ -324,3 +376,154 @@ func (h *UserHandler) SetLocality(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+type GetStaffResponse struct {
+	Users []user.User `json:"users"`
+}
+
+func (h *UserHandler) GetStaff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	staff, err := h.user.GetStaff(ctx)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to get staff")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	resp := GetStaffResponse{Users: staff}
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SetRoleResponse struct {
+	User user.User `json:"user"`
+}
+
+func (h *UserHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := SetRoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrUnmarshalRequest)
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	userData, err := h.user.SetRole(ctx, mux.Vars(r)["username"], req.Role)
+	if err != nil {
+		switch {
+		case goerrors.Is(err, user.ErrUserNotFound):
+			utils.LogError(ctx, err, "user not found")
+			http.Error(w, utils.NotFound, http.StatusNotFound)
+		case goerrors.Is(err, user.ErrInvalidRole), goerrors.Is(err, user.ErrLastAdmin):
+			utils.LogError(ctx, err, "failed to set role")
+			http.Error(w, utils.Invalid, http.StatusBadRequest)
+		default:
+			utils.LogError(ctx, err, "failed to set role")
+			http.Error(w, utils.Internal, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.LogInfoMessage(ctx, fmt.Sprintf("user %s got role %s from %s", userData.Username, userData.Role, utils.GetUsernameFromContext(ctx)))
+
+	resp := SetRoleResponse{User: userData}
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+type GetSessionsResponse struct {
+	Sessions []user.Session `json:"sessions"`
+}
+
+func (h *UserHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessions, err := h.session.GetSessions(ctx, utils.GetUsernameFromContext(ctx))
+	if err != nil {
+		utils.LogError(ctx, err, "failed to get sessions")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	resp := GetSessionsResponse{Sessions: sessions}
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionID, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		utils.LogError(ctx, err, "invalid session id")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.session.RevokeSession(ctx, utils.GetUsernameFromContext(ctx), sessionID); err != nil {
+		if goerrors.Is(err, user.ErrSessionNotFound) {
+			utils.LogError(ctx, err, "session not found")
+			http.Error(w, utils.NotFound, http.StatusNotFound)
+		} else {
+			utils.LogError(ctx, err, "failed to revoke session")
+			http.Error(w, utils.Internal, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.LogInfoMessage(ctx, fmt.Sprintf("session %s revoked", sessionID))
+}
+
+func (h *UserHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := h.session.RevokeOtherSessions(ctx, utils.GetUsernameFromContext(ctx)); err != nil {
+		utils.LogError(ctx, err, "failed to revoke sessions")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	utils.LogInfoMessage(ctx, "other sessions revoked")
+}
+
+// getDevice describes the client of the request, nginx passes the client address in X-Real-IP.
+func getDevice(r *http.Request) user.Device {
+	ip := r.Header.Get("X-Real-IP")
+	if ip == "" {
+		ip = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+	}
+
+	return user.Device{UserAgent: r.UserAgent(), IP: ip}
+}
+
+// setAccessToken hands the access token to the client both in the Authorization header and in the cookie.
+func (h *UserHandler) setAccessToken(w http.ResponseWriter, accessToken string) {
+	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.sessionCfg.AccessTokenCookieName,
+		Secure:   h.sessionCfg.ProtectedCookies,
+		Value:    accessToken,
+		HttpOnly: true,
+		Expires:  time.Now().Local().Add(h.sessionCfg.AccessTokenLifeTime),
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
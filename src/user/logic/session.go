@@ -2,8 +2,12 @@ package logic
 
 import (
 	"context"
+	goerrors "errors"
+	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/satori/uuid"
 	"pet_adopter/src/config"
 	"pet_adopter/src/user"
 	"pet_adopter/src/utils"
@@ -21,38 +25,170 @@ func NewSessionLogic(session user.SessionRepo, cfg config.SessionConfig) *Sessio
 	}
 }
 
-func (logic *SessionLogic) CheckSession(ctx context.Context, username string, token string) (bool, error) {
-	accessToken, err := logic.session.GetAccessToken(ctx, username)
+// CheckSession finds the session of the access token and marks it as seen now, the token alone identifies the user.
+func (logic *SessionLogic) CheckSession(ctx context.Context, token string) (user.Session, bool, error) {
+	session, err := logic.session.GetSessionByAccessToken(ctx, token)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to get access token")
+		if goerrors.Is(err, user.ErrSessionNotFound) {
+			return user.Session{}, false, nil
+		}
+		return user.Session{}, false, errors.Wrap(err, "failed to get session")
 	}
 
-	return token == accessToken, nil
+	session.LastSeenAt = time.Now().Local()
+	if err = logic.session.SetLastSeen(ctx, session.ID, session.LastSeenAt); err != nil {
+		return user.Session{}, false, errors.Wrap(err, "failed to update session")
+	}
+
+	return session, true, nil
+}
+
+// SetSession signs the user in on one more device, the sessions on the other devices stay active.
+func (logic *SessionLogic) SetSession(ctx context.Context, username string, device user.Device) (string, string, error) {
+	accessToken, refreshToken, err := logic.generateTokens()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().Local()
+	session := user.Session{
+		ID:           uuid.NewV4(),
+		Username:     username,
+		UserAgent:    device.UserAgent,
+		IP:           device.IP,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		CreatedAt:    now,
+		LastSeenAt:   now,
+	}
+
+	if err = logic.session.CreateSession(ctx, session, logic.cfg.AccessTokenLifeTime, logic.cfg.RefreshTokenLifeTime); err != nil {
+		return "", "", errors.Wrap(err, "failed to create session")
+	}
+
+	return session.AccessToken, session.RefreshToken, nil
 }
 
-func (logic *SessionLogic) SetSession(ctx context.Context, username string) (string, string, error) {
-	accessToken := utils.GenerateSessionToken(logic.cfg.AccessTokenLength)
-	if err := logic.session.SetAccessToken(ctx, username, accessToken, logic.cfg.AccessTokenLifeTime); err != nil {
-		return "", "", errors.Wrap(err, "failed to set access token")
+// RefreshSession exchanges the refresh token for a new pair, every refresh token works once. A replayed token
+// means it leaked, so the whole session descending from that login is revoked.
+func (logic *SessionLogic) RefreshSession(ctx context.Context, refreshToken string) (string, string, error) {
+	session, err := logic.session.GetSessionByRefreshToken(ctx, refreshToken)
+	if goerrors.Is(err, user.ErrSessionNotFound) {
+		return "", "", logic.checkReuse(ctx, refreshToken)
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to get session")
 	}
 
-	refreshToken := utils.GenerateSessionToken(logic.cfg.RefreshTokenLength)
-	if err := logic.session.SetRefreshToken(ctx, username, refreshToken); err != nil {
-		return "", "", errors.Wrap(err, "failed to set refresh token")
+	accessToken, newRefreshToken, err := logic.generateTokens()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = logic.session.RotateTokens(ctx, session, accessToken, newRefreshToken, logic.cfg.AccessTokenLifeTime, logic.cfg.RefreshTokenLifeTime)
+	if goerrors.Is(err, user.ErrRefreshTokenReused) {
+		return "", "", logic.revokeFamily(ctx, session)
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to rotate session tokens")
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+func (logic *SessionLogic) generateTokens() (string, string, error) {
+	accessToken, err := utils.GenerateToken(logic.cfg.AccessToken)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate access token")
+	}
+
+	refreshToken, err := utils.GenerateToken(logic.cfg.RefreshToken)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate refresh token")
 	}
 
 	return accessToken, refreshToken, nil
 }
 
-func (logic *SessionLogic) RefreshSession(ctx context.Context, username string, refreshToken string) (string, string, error) {
-	token, err := logic.session.GetRefreshToken(ctx, username)
+// checkReuse tells a replayed refresh token from an unknown one and revokes the session of the former.
+func (logic *SessionLogic) checkReuse(ctx context.Context, refreshToken string) error {
+	session, err := logic.session.GetSessionByUsedRefreshToken(ctx, refreshToken)
+	if goerrors.Is(err, user.ErrSessionNotFound) {
+		return user.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to get session of used refresh token")
+	}
+
+	return logic.revokeFamily(ctx, session)
+}
+
+func (logic *SessionLogic) revokeFamily(ctx context.Context, session user.Session) error {
+	current, err := logic.session.GetUserSessions(ctx, session.Username)
+	if err != nil {
+		return errors.Wrap(err, "failed to get sessions")
+	}
+
+	// The tokens stored with the session are the latest ones, the ones passed in may be already rotated.
+	for _, row := range current {
+		if row.ID == session.ID {
+			if err = logic.session.RemoveSession(ctx, row); err != nil {
+				return errors.Wrap(err, "failed to remove session")
+			}
+		}
+	}
+
+	utils.LogInfoMessage(ctx, fmt.Sprintf("refresh token of session %s reused, session revoked", session.ID))
+	return user.ErrRefreshTokenReused
+}
+
+// GetSessions lists the active sessions of the user, the one of the current request is marked.
+func (logic *SessionLogic) GetSessions(ctx context.Context, username string) ([]user.Session, error) {
+	sessions, err := logic.session.GetUserSessions(ctx, username)
 	if err != nil {
-		return "", "", errors.Wrap(err, "failed to get refresh token")
+		return nil, errors.Wrap(err, "failed to get sessions")
+	}
+
+	currentID := utils.GetSessionIDFromContext(ctx)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
 	}
 
-	if refreshToken != token {
-		return "", "", user.ErrInvalidRefreshToken
+	return sessions, nil
+}
+
+func (logic *SessionLogic) RevokeSession(ctx context.Context, username string, id uuid.UUID) error {
+	sessions, err := logic.session.GetUserSessions(ctx, username)
+	if err != nil {
+		return errors.Wrap(err, "failed to get sessions")
+	}
+
+	for _, session := range sessions {
+		if session.ID == id {
+			return logic.session.RemoveSession(ctx, session)
+		}
+	}
+
+	return user.ErrSessionNotFound
+}
+
+// RevokeOtherSessions signs the user out everywhere except the device of the current request.
+func (logic *SessionLogic) RevokeOtherSessions(ctx context.Context, username string) error {
+	sessions, err := logic.session.GetUserSessions(ctx, username)
+	if err != nil {
+		return errors.Wrap(err, "failed to get sessions")
+	}
+
+	currentID := utils.GetSessionIDFromContext(ctx)
+	for _, session := range sessions {
+		if session.ID == currentID {
+			continue
+		}
+
+		if err = logic.session.RemoveSession(ctx, session); err != nil {
+			return errors.Wrap(err, "failed to remove session")
+		}
 	}
 
-	return logic.SetSession(ctx, username)
+	return nil
 }
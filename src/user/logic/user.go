@@ -3,10 +3,14 @@ package logic
 import (
 	"context"
 	goerrors "errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/satori/uuid"
+	"pet_adopter/src/audit"
+	"pet_adopter/src/config"
 	"pet_adopter/src/locality"
 	"pet_adopter/src/user"
 	"pet_adopter/src/utils"
@@ -15,10 +19,19 @@ import (
 type UserLogic struct {
 	repo         user.UserRepo
 	localityRepo locality.LocalityRepo
+	auditLog     audit.AuditLogic
+	passwordCfg  config.PasswordConfig
+	dummyHash    string
 }
 
-func NewUserLogic(repo user.UserRepo, localityRepo locality.LocalityRepo) *UserLogic {
-	return &UserLogic{repo: repo, localityRepo: localityRepo}
+func NewUserLogic(repo user.UserRepo, localityRepo locality.LocalityRepo, auditLog audit.AuditLogic, passwordCfg config.PasswordConfig) *UserLogic {
+	return &UserLogic{
+		repo:         repo,
+		localityRepo: localityRepo,
+		auditLog:     auditLog,
+		passwordCfg:  passwordCfg,
+		dummyHash:    utils.DummyPasswordHash(passwordCfg),
+	}
 }
 
 func (logic *UserLogic) GetUserByID(ctx context.Context, id uuid.UUID) (user.User, error) {
@@ -30,15 +43,21 @@ func (logic *UserLogic) GetUserByUsername(ctx context.Context, username string)
 }
 
 func (logic *UserLogic) CreateUser(ctx context.Context, username string, password string) (user.User, error) {
+	passwordHash, err := utils.HashPassword(password, logic.passwordCfg)
+	if err != nil {
+		return user.User{}, errors.Wrap(err, "failed to hash password")
+	}
+
 	userData := user.User{
 		ID:           uuid.NewV4(),
 		Username:     username,
-		PasswordHash: utils.GetPasswordHash(password),
+		PasswordHash: passwordHash,
 		LocalityID:   uuid.Nil,
+		Role:         user.RoleUser,
 		CreatedAt:    time.Now().Local(),
 	}
 
-	if err := logic.repo.CreateUser(ctx, userData); err != nil {
+	if err = logic.repo.CreateUser(ctx, userData); err != nil {
 		return user.User{}, err
 	}
 
@@ -61,11 +80,63 @@ func (logic *UserLogic) SetLocalityID(ctx context.Context, id uuid.UUID, localit
 	return logic.repo.GetUserByID(ctx, id)
 }
 
+func (logic *UserLogic) SetRole(ctx context.Context, username string, role string) (user.User, error) {
+	if !slices.Contains(user.Roles, role) {
+		return user.User{}, user.ErrInvalidRole
+	}
+
+	userData, err := logic.repo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return user.User{}, errors.Wrap(err, "failed to get user")
+	}
+
+	if err = logic.repo.SetRole(ctx, userData.ID, role); err != nil {
+		return user.User{}, err
+	}
+
+	before := userData
+	userData.Role = role
+	if before.Role != role {
+		logic.auditLog.Record(ctx, audit.ActionRoleChange, audit.EntityUser, userData.ID, before, userData)
+	}
+
+	return userData, nil
+}
+
+func (logic *UserLogic) GetStaff(ctx context.Context) ([]user.User, error) {
+	return logic.repo.GetStaff(ctx)
+}
+
 func (logic *UserLogic) CheckPassword(ctx context.Context, username string, password string) (user.User, bool, error) {
 	userData, err := logic.repo.GetUserByUsername(ctx, username)
 	if err != nil {
+		// An unknown username costs the same hashing as a wrong password, the response time does not reveal it.
+		if goerrors.Is(err, user.ErrUserNotFound) {
+			_, _, _ = utils.CheckPassword(password, logic.dummyHash, logic.passwordCfg)
+		}
 		return user.User{}, false, errors.Wrap(err, "failed to get user data")
 	}
 
-	return userData, utils.GetPasswordHash(password) == userData.PasswordHash, nil
+	match, needsRehash, err := utils.CheckPassword(password, userData.PasswordHash, logic.passwordCfg)
+	if err != nil {
+		return user.User{}, false, errors.Wrap(err, "failed to check password")
+	}
+
+	// Legacy and outdated hashes are replaced while the plain password is at hand, the login goes on regardless.
+	if match && needsRehash {
+		logic.rehashPassword(ctx, userData, password)
+	}
+
+	return userData, match, nil
+}
+
+func (logic *UserLogic) rehashPassword(ctx context.Context, userData user.User, password string) {
+	passwordHash, err := utils.HashPassword(password, logic.passwordCfg)
+	if err == nil {
+		err = logic.repo.SetPasswordHash(ctx, userData.ID, userData.PasswordHash, passwordHash)
+	}
+
+	if err != nil {
+		utils.LogError(ctx, err, fmt.Sprintf("failed to rehash password of user %s", userData.Username))
+	}
 }
@@ -14,10 +14,25 @@ import (
 )
 
 const (
-	getUserByID       = `SELECT id, username, password_hash, locality_id, created_at FROM MyUser WHERE id = $1;`
-	getUserByUsername = `SELECT id, username, password_hash, locality_id, created_at FROM MyUser WHERE username = $1;`
-	createUser        = `INSERT INTO MyUser (id, username, password_hash, locality_id, created_at) VALUES ($1, $2, $3, $4, $5);`
+	getUserByID       = `SELECT id, username, password_hash, locality_id, role, created_at FROM MyUser WHERE id = $1;`
+	getUserByUsername = `SELECT id, username, password_hash, locality_id, role, created_at FROM MyUser WHERE username = $1;`
+	getStaff          = `SELECT id, username, password_hash, locality_id, role, created_at FROM MyUser WHERE role <> 'user' ORDER BY role, username;`
+	createUser        = `INSERT INTO MyUser (id, username, password_hash, locality_id, role, created_at) VALUES ($1, $2, $3, $4, $5, $6);`
 	setLocalityID     = `UPDATE MyUser SET locality_id = $1 WHERE id = $2;`
+	// setPasswordHash replaces only the hash that was checked, a concurrent change wins.
+	setPasswordHash = `UPDATE MyUser SET password_hash = $1 WHERE id = $2 AND password_hash = $3;`
+
+	// setRole refuses to demote the last admin, so the roles can always be managed. The admin rows are locked
+	// in one order before the check, a concurrent demotion waits and then sees the admins left after it.
+	setRole = `
+WITH admins AS (
+	SELECT id FROM MyUser WHERE role = 'admin' ORDER BY id FOR UPDATE
+)
+UPDATE MyUser SET role = $1
+WHERE id = $2 AND (
+	role <> 'admin' OR $1 = 'admin' OR
+	EXISTS (SELECT 1 FROM admins WHERE admins.id <> $2)
+);`
 )
 
 type UserPostgres struct {
@@ -37,6 +52,7 @@ func (repo *UserPostgres) GetUserByID(ctx context.Context, id uuid.UUID) (user.U
 		&result.Username,
 		&result.PasswordHash,
 		&localityID,
+		&result.Role,
 		&result.CreatedAt,
 	); err != nil {
 		if goerrors.Is(err, pgx.ErrNoRows) {
@@ -58,6 +74,7 @@ func (repo *UserPostgres) GetUserByUsername(ctx context.Context, username string
 		&result.Username,
 		&result.PasswordHash,
 		&localityID,
+		&result.Role,
 		&result.CreatedAt,
 	); err != nil {
 		if goerrors.Is(err, pgx.ErrNoRows) {
@@ -81,6 +98,7 @@ func (repo *UserPostgres) CreateUser(ctx context.Context, userData user.User) er
 		userData.Username,
 		userData.PasswordHash,
 		localityID,
+		userData.Role,
 		userData.CreatedAt,
 	); err != nil {
 		if strings.HasSuffix(err.Error(), "(SQLSTATE 23505)") {
@@ -99,3 +117,48 @@ func (repo *UserPostgres) SetLocalityID(ctx context.Context, id uuid.UUID, local
 
 	return nil
 }
+
+func (repo *UserPostgres) SetPasswordHash(ctx context.Context, id uuid.UUID, oldHash string, newHash string) error {
+	if _, err := repo.db.Exec(ctx, setPasswordHash, newHash, id, oldHash); err != nil {
+		return errors.Wrap(err, "failed to set password hash")
+	}
+
+	return nil
+}
+
+func (repo *UserPostgres) SetRole(ctx context.Context, id uuid.UUID, role string) error {
+	tag, err := repo.db.Exec(ctx, setRole, role, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to set role")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return user.ErrLastAdmin
+	}
+
+	return nil
+}
+
+func (repo *UserPostgres) GetStaff(ctx context.Context) ([]user.User, error) {
+	result := make([]user.User, 0)
+
+	rows, err := repo.db.Query(ctx, getStaff)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get staff from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row        user.User
+			localityID []byte
+		)
+		if err = rows.Scan(&row.ID, &row.Username, &row.PasswordHash, &localityID, &row.Role, &row.CreatedAt); err != nil {
+			return result, errors.Wrap(err, "failed to parse user")
+		}
+		row.LocalityID = uuid.FromBytesOrNil(localityID)
+		result = append(result, row)
+	}
+
+	return result, nil
+}
@@ -8,8 +8,44 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
+	"github.com/satori/uuid"
+	"pet_adopter/src/user"
 )
 
+// setLastSeen does not recreate a session that expired in the meantime, a plain HSET would.
+var setLastSeen = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+end
+return 1
+`)
+
+// rotateTokens swaps the tokens only while the session still has the presented refresh token,
+// two concurrent refreshes with one token can not both succeed.
+var rotateTokens = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "refresh_token") ~= ARGV[2] then
+	return 0
+end
+redis.call("DEL", KEYS[2], KEYS[3])
+redis.call("SET", KEYS[4], ARGV[1], "PX", ARGV[6])
+redis.call("HSET", KEYS[1], "access_token", ARGV[3], "refresh_token", ARGV[4])
+redis.call("PEXPIRE", KEYS[1], ARGV[6])
+redis.call("SET", KEYS[5], ARGV[1], "PX", ARGV[5])
+redis.call("SET", KEYS[6], ARGV[1], "PX", ARGV[6])
+return 1
+`)
+
+// sessionHash is the layout of the session:<id> hash, the tokens point back to it with their own keys.
+type sessionHash struct {
+	Username     string    `redis:"username"`
+	UserAgent    string    `redis:"user_agent"`
+	IP           string    `redis:"ip"`
+	AccessToken  string    `redis:"access_token"`
+	RefreshToken string    `redis:"refresh_token"`
+	CreatedAt    time.Time `redis:"created_at"`
+	LastSeenAt   time.Time `redis:"last_seen_at"`
+}
+
 type SessionRedis struct {
 	client *redis.Client
 }
@@ -18,66 +54,187 @@ func NewSessionRedis(client *redis.Client) *SessionRedis {
 	return &SessionRedis{client: client}
 }
 
-func (s *SessionRedis) SetAccessToken(ctx context.Context, username string, token string, lifeTime time.Duration) error {
-	if err := s.client.Set(ctx, getAccessTokenKey(username), token, lifeTime).Err(); err != nil {
-		return errors.Wrap(err, "failed to set access token")
+func (s *SessionRedis) CreateSession(ctx context.Context, session user.Session, accessLifeTime time.Duration, lifeTime time.Duration) error {
+	hash := sessionHash{
+		Username:     session.Username,
+		UserAgent:    session.UserAgent,
+		IP:           session.IP,
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		CreatedAt:    session.CreatedAt,
+		LastSeenAt:   session.LastSeenAt,
+	}
+
+	if _, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, getSessionKey(session.ID), hash)
+		pipe.Expire(ctx, getSessionKey(session.ID), lifeTime)
+		pipe.Set(ctx, getAccessTokenKey(session.AccessToken), session.ID.String(), accessLifeTime)
+		pipe.Set(ctx, getRefreshTokenKey(session.RefreshToken), session.ID.String(), lifeTime)
+		pipe.SAdd(ctx, getUserSessionsKey(session.Username), session.ID.String())
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "failed to create session")
+	}
+
+	return nil
+}
+
+func (s *SessionRedis) GetSessionByAccessToken(ctx context.Context, token string) (user.Session, error) {
+	return s.getSessionByTokenKey(ctx, getAccessTokenKey(token))
+}
+
+func (s *SessionRedis) GetSessionByRefreshToken(ctx context.Context, token string) (user.Session, error) {
+	return s.getSessionByTokenKey(ctx, getRefreshTokenKey(token))
+}
+
+func (s *SessionRedis) GetSessionByUsedRefreshToken(ctx context.Context, token string) (user.Session, error) {
+	return s.getSessionByTokenKey(ctx, getUsedRefreshTokenKey(token))
+}
+
+// RotateTokens gives the session new tokens and prolongs it. The old refresh token is kept as used, so its
+// replay can be told from a forged token. ErrRefreshTokenReused means the session was rotated in the meantime.
+func (s *SessionRedis) RotateTokens(ctx context.Context, session user.Session, accessToken string, refreshToken string, accessLifeTime time.Duration, lifeTime time.Duration) error {
+	keys := []string{
+		getSessionKey(session.ID),
+		getAccessTokenKey(session.AccessToken),
+		getRefreshTokenKey(session.RefreshToken),
+		getUsedRefreshTokenKey(session.RefreshToken),
+		getAccessTokenKey(accessToken),
+		getRefreshTokenKey(refreshToken),
+	}
+
+	rotated, err := rotateTokens.Run(ctx, s.client, keys,
+		session.ID.String(), session.RefreshToken, accessToken, refreshToken,
+		accessLifeTime.Milliseconds(), lifeTime.Milliseconds(),
+	).Int()
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate session tokens")
+	}
+
+	if rotated == 0 {
+		return user.ErrRefreshTokenReused
 	}
 
 	return nil
 }
 
-func (s *SessionRedis) SetRefreshToken(ctx context.Context, username string, token string) error {
-	if err := s.client.Set(ctx, getRefreshTokenKey(username), token, 0).Err(); err != nil {
-		return errors.Wrap(err, "failed to set refresh token")
+func (s *SessionRedis) SetLastSeen(ctx context.Context, id uuid.UUID, lastSeen time.Time) error {
+	if err := setLastSeen.Run(ctx, s.client, []string{getSessionKey(id)}, lastSeen.Format(time.RFC3339Nano)).Err(); err != nil {
+		return errors.Wrap(err, "failed to set session last seen time")
 	}
 
 	return nil
 }
 
-func (s *SessionRedis) GetAccessToken(ctx context.Context, username string) (string, error) {
-	token, err := s.client.Get(ctx, getAccessTokenKey(username)).Result()
+// GetUserSessions returns the active sessions of the user, the expired ones are dropped from the user's set.
+func (s *SessionRedis) GetUserSessions(ctx context.Context, username string) ([]user.Session, error) {
+	result := make([]user.Session, 0)
+
+	ids, err := s.client.SMembers(ctx, getUserSessionsKey(username)).Result()
 	if err != nil {
-		if goerrors.Is(err, redis.Nil) {
-			return "", nil
+		return result, errors.Wrap(err, "failed to get user sessions")
+	}
+
+	expired := make([]interface{}, 0)
+	for _, idString := range ids {
+		id, err := uuid.FromString(idString)
+		if err != nil {
+			expired = append(expired, idString)
+			continue
 		}
-		return "", errors.Wrap(err, "failed to get access token")
+
+		session, err := s.getSession(ctx, id)
+		if err != nil {
+			if goerrors.Is(err, user.ErrSessionNotFound) {
+				expired = append(expired, idString)
+				continue
+			}
+			return result, err
+		}
+		result = append(result, session)
+	}
+
+	if len(expired) > 0 {
+		if err = s.client.SRem(ctx, getUserSessionsKey(username), expired...).Err(); err != nil {
+			return result, errors.Wrap(err, "failed to remove expired sessions")
+		}
+	}
+
+	return result, nil
+}
+
+func (s *SessionRedis) RemoveSession(ctx context.Context, session user.Session) error {
+	if _, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, getSessionKey(session.ID), getAccessTokenKey(session.AccessToken), getRefreshTokenKey(session.RefreshToken))
+		pipe.SRem(ctx, getUserSessionsKey(session.Username), session.ID.String())
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "failed to remove session")
 	}
 
-	return token, nil
+	return nil
 }
 
-func (s *SessionRedis) GetRefreshToken(ctx context.Context, username string) (string, error) {
-	token, err := s.client.Get(ctx, getRefreshTokenKey(username)).Result()
+func (s *SessionRedis) getSessionByTokenKey(ctx context.Context, key string) (user.Session, error) {
+	idString, err := s.client.Get(ctx, key).Result()
 	if err != nil {
 		if goerrors.Is(err, redis.Nil) {
-			return "", nil
+			return user.Session{}, user.ErrSessionNotFound
 		}
-		return "", errors.Wrap(err, "failed to get refresh token")
+		return user.Session{}, errors.Wrap(err, "failed to get session id")
 	}
 
-	return token, nil
+	id, err := uuid.FromString(idString)
+	if err != nil {
+		return user.Session{}, errors.Wrap(err, "invalid session id")
+	}
+
+	return s.getSession(ctx, id)
 }
 
-func (s *SessionRedis) RemoveAccessToken(ctx context.Context, username string) error {
-	if err := s.client.Del(ctx, getAccessTokenKey(username)).Err(); err != nil {
-		return errors.Wrap(err, "failed to remove access token")
+func (s *SessionRedis) getSession(ctx context.Context, id uuid.UUID) (user.Session, error) {
+	res := s.client.HGetAll(ctx, getSessionKey(id))
+	if err := res.Err(); err != nil {
+		return user.Session{}, errors.Wrap(err, "failed to get session")
 	}
 
-	return nil
-}
+	if len(res.Val()) == 0 {
+		return user.Session{}, user.ErrSessionNotFound
+	}
 
-func (s *SessionRedis) RemoveRefreshToken(ctx context.Context, username string) error {
-	if err := s.client.Del(ctx, getRefreshTokenKey(username)).Err(); err != nil {
-		return errors.Wrap(err, "failed to remove refresh token")
+	var hash sessionHash
+	if err := res.Scan(&hash); err != nil {
+		return user.Session{}, errors.Wrap(err, "failed to parse session")
 	}
 
-	return nil
+	return user.Session{
+		ID:           id,
+		Username:     hash.Username,
+		UserAgent:    hash.UserAgent,
+		IP:           hash.IP,
+		AccessToken:  hash.AccessToken,
+		RefreshToken: hash.RefreshToken,
+		CreatedAt:    hash.CreatedAt,
+		LastSeenAt:   hash.LastSeenAt,
+	}, nil
+}
+
+func getSessionKey(id uuid.UUID) string {
+	return fmt.Sprintf("session:%s", id)
+}
+
+func getUserSessionsKey(username string) string {
+	return fmt.Sprintf("sessions:%s", username)
+}
+
+func getAccessTokenKey(token string) string {
+	return fmt.Sprintf("session_access:%s", token)
 }
 
-func getAccessTokenKey(username string) string {
-	return fmt.Sprintf("access:%s", username)
+func getRefreshTokenKey(token string) string {
+	return fmt.Sprintf("session_refresh:%s", token)
 }
 
-func getRefreshTokenKey(username string) string {
-	return fmt.Sprintf("refresh:%s", username)
+func getUsedRefreshTokenKey(token string) string {
+	return fmt.Sprintf("session_refresh_used:%s", token)
 }
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,31 +12,85 @@ import (
 var (
 	ErrUserNotFound        = errors.New("user not found")
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
 	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrLastAdmin           = errors.New("can not demote the last admin")
+	ErrSessionNotFound     = errors.New("session not found")
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+const (
+	PermManageCatalog = "manage_catalog"
+	PermDeleteAds     = "delete_ads"
+	PermManageRoles   = "manage_roles"
+	PermModerateAds   = "moderate_ads"
+	PermViewAudit     = "view_audit"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser:      {},
+	RoleModerator: {PermDeleteAds, PermModerateAds},
+	RoleAdmin:     {PermManageCatalog, PermDeleteAds, PermManageRoles, PermModerateAds, PermViewAudit},
+}
+
+func HasPermission(role string, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
 type User struct {
 	ID           uuid.UUID `json:"-"`
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"`
 	LocalityID   uuid.UUID `json:"-"`
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"-"`
 }
 
+// Session is one signed in device of the user, the tokens are never sent back in the session list.
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"-"`
+	UserAgent    string    `json:"user_agent"`
+	IP           string    `json:"ip"`
+	AccessToken  string    `json:"-"`
+	RefreshToken string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+	Current      bool      `json:"current"`
+}
+
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
 type UserRepo interface {
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	CreateUser(ctx context.Context, user User) error
 	SetLocalityID(ctx context.Context, id uuid.UUID, localityID uuid.UUID) error
+	SetPasswordHash(ctx context.Context, id uuid.UUID, oldHash string, newHash string) error
+	SetRole(ctx context.Context, id uuid.UUID, role string) error
+	GetStaff(ctx context.Context) ([]User, error)
 }
 
 type SessionRepo interface {
-	SetAccessToken(ctx context.Context, username string, token string, lifeTime time.Duration) error
-	SetRefreshToken(ctx context.Context, username string, token string) error
-	GetAccessToken(ctx context.Context, username string) (string, error)
-	GetRefreshToken(ctx context.Context, username string) (string, error)
-	RemoveAccessToken(ctx context.Context, username string) error
-	RemoveRefreshToken(ctx context.Context, username string) error
+	CreateSession(ctx context.Context, session Session, accessLifeTime time.Duration, lifeTime time.Duration) error
+	GetSessionByAccessToken(ctx context.Context, token string) (Session, error)
+	GetSessionByRefreshToken(ctx context.Context, token string) (Session, error)
+	GetSessionByUsedRefreshToken(ctx context.Context, token string) (Session, error)
+	RotateTokens(ctx context.Context, session Session, accessToken string, refreshToken string, accessLifeTime time.Duration, lifeTime time.Duration) error
+	SetLastSeen(ctx context.Context, id uuid.UUID, lastSeen time.Time) error
+	GetUserSessions(ctx context.Context, username string) ([]Session, error)
+	RemoveSession(ctx context.Context, session Session) error
 }
 
 type UserLogic interface {
@@ -44,9 +99,15 @@ type UserLogic interface {
 	CreateUser(ctx context.Context, username string, password string) (User, error)
 	SetLocalityID(ctx context.Context, id uuid.UUID, localityID uuid.UUID) (User, error)
 	CheckPassword(ctx context.Context, username string, password string) (User, bool, error)
+	SetRole(ctx context.Context, username string, role string) (User, error)
+	GetStaff(ctx context.Context) ([]User, error)
 }
 
 type SessionLogic interface {
-	CheckSession(ctx context.Context, username string, token string) (bool, error)
-	SetSession(ctx context.Context, username string) (string, string, error)
+	CheckSession(ctx context.Context, token string) (Session, bool, error)
+	SetSession(ctx context.Context, username string, device Device) (string, string, error)
+	RefreshSession(ctx context.Context, refreshToken string) (string, string, error)
+	GetSessions(ctx context.Context, username string) ([]Session, error)
+	RevokeSession(ctx context.Context, username string, id uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, username string) error
 }
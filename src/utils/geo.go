@@ -0,0 +1,31 @@
+package utils
+
+import "math"
+
+const kmPerLatitudeDegree = math.Pi * 6371 / 180
+
+func ValidCoordinates(latitude float64, longitude float64) bool {
+	return math.Abs(latitude) <= 90 && math.Abs(longitude) <= 180
+}
+
+// BoundingBox returns the latitude and longitude ranges containing every point within radiusKm of the centre.
+// The whole longitude range is returned when the box reaches a pole or wraps around the antimeridian.
+func BoundingBox(latitude float64, longitude float64, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	deltaLat := radiusKm / kmPerLatitudeDegree
+	minLat = latitude - deltaLat
+	maxLat = latitude + deltaLat
+
+	if minLat <= -90 || maxLat >= 90 {
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180
+	}
+
+	deltaLon := deltaLat / math.Cos(latitude*math.Pi/180)
+	minLon = longitude - deltaLon
+	maxLon = longitude + deltaLon
+
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+
+	return minLat, maxLat, minLon, maxLon
+}
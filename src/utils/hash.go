@@ -1,26 +1,143 @@
 package utils
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
-	"math/rand"
-	"time"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"pet_adopter/src/config"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+
+	// The lowest argon2id cost accepted in the config, it follows the OWASP minimum of 19 MiB and 2 iterations.
+	MinPasswordMemory      = 19 * 1024
+	MinPasswordIterations  = 2
+	MinPasswordParallelism = 1
+	MinPasswordSaltLength  = 16
+	MinPasswordKeyLength   = 16
+)
+
+var (
+	ErrInvalidPasswordHash = errors.New("invalid password hash")
+	ErrWeakPasswordConfig  = errors.New("password hashing cost is too low")
 )
 
-const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+// ValidatePasswordConfig rejects the costs that make argon2id panic or produce weak hashes,
+// so a misconfiguration stops the startup instead of the first signup.
+func ValidatePasswordConfig(cfg config.PasswordConfig) error {
+	switch {
+	case cfg.Memory < MinPasswordMemory:
+		return errors.Wrapf(ErrWeakPasswordConfig, "memory %d KiB, want at least %d", cfg.Memory, MinPasswordMemory)
+	case cfg.Iterations < MinPasswordIterations:
+		return errors.Wrapf(ErrWeakPasswordConfig, "iterations %d, want at least %d", cfg.Iterations, MinPasswordIterations)
+	case cfg.Parallelism < MinPasswordParallelism:
+		return errors.Wrapf(ErrWeakPasswordConfig, "parallelism %d, want at least %d", cfg.Parallelism, MinPasswordParallelism)
+	case cfg.SaltLength < MinPasswordSaltLength:
+		return errors.Wrapf(ErrWeakPasswordConfig, "salt length %d, want at least %d", cfg.SaltLength, MinPasswordSaltLength)
+	case cfg.KeyLength < MinPasswordKeyLength:
+		return errors.Wrapf(ErrWeakPasswordConfig, "key length %d, want at least %d", cfg.KeyLength, MinPasswordKeyLength)
+	}
+
+	return nil
+}
+
+// HashPassword hashes the password with argon2id and a random salt. The result is a PHC string,
+// so the cost and the salt are stored along with the hash.
+func HashPassword(password string, cfg config.PasswordConfig) (string, error) {
+	if err := ValidatePasswordConfig(cfg); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, cfg.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(err, "failed to generate salt")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, cfg.Iterations, cfg.Memory, cfg.Parallelism, cfg.KeyLength)
+
+	return encodeArgon2idHash(salt, key, cfg), nil
+}
+
+// DummyPasswordHash returns an argon2id hash with the cost of cfg that no password matches. Checking
+// a password against it takes as long as against a real hash, so unknown usernames can not be told by timing.
+func DummyPasswordHash(cfg config.PasswordConfig) string {
+	return encodeArgon2idHash(make([]byte, cfg.SaltLength), make([]byte, cfg.KeyLength), cfg)
+}
+
+// CheckPassword compares the password with the stored hash in constant time. needsRehash is set for
+// the legacy unsalted SHA-256 hashes and for the argon2id ones made with another cost than cfg.
+func CheckPassword(password string, hash string, cfg config.PasswordConfig) (bool, bool, error) {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		legacyHash := legacyPasswordHash(password)
+		return subtle.ConstantTimeCompare([]byte(legacyHash), []byte(hash)) == 1, true, nil
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false, false, err
+	}
+
+	passwordKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	match := subtle.ConstantTimeCompare(key, passwordKey) == 1
+
+	needsRehash := params.Memory != cfg.Memory || params.Iterations != cfg.Iterations || params.Parallelism != cfg.Parallelism ||
+		uint32(len(salt)) != cfg.SaltLength || uint32(len(key)) != cfg.KeyLength
+
+	return match, needsRehash, nil
+}
+
+func encodeArgon2idHash(salt []byte, key []byte, cfg config.PasswordConfig) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, cfg.Memory, cfg.Iterations, cfg.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2idHash(hash string) (config.PasswordConfig, []byte, []byte, error) {
+	params := config.PasswordConfig{}
+
+	// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	// Zero iterations or parallelism make argon2 panic.
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
 
-func GetPasswordHash(password string) string {
+	return params, salt, key, nil
+}
+
+// legacyPasswordHash is the unsalted hash of the accounts created before argon2id, it is only checked.
+func legacyPasswordHash(password string) string {
 	hash := sha256.New()
 	hash.Write([]byte(password))
 	hashInBytes := hash.Sum(nil)
 	return hex.EncodeToString(hashInBytes)
 }
-
-func GenerateSessionToken(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	result := make([]byte, length)
-	for i := range result {
-		result[i] = chars[rand.Intn(len(chars))]
-	}
-	return string(result)
-}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	goerrors "errors"
+	"testing"
+
+	"pet_adopter/src/config"
+)
+
+var testPasswordConfig = config.PasswordConfig{
+	Memory:      MinPasswordMemory,
+	Iterations:  MinPasswordIterations,
+	Parallelism: MinPasswordParallelism,
+	SaltLength:  MinPasswordSaltLength,
+	KeyLength:   MinPasswordKeyLength,
+}
+
+func TestValidatePasswordConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.PasswordConfig)
+		err    error
+	}{
+		{name: "minimal cost", modify: func(cfg *config.PasswordConfig) {}, err: nil},
+		{name: "zero config", modify: func(cfg *config.PasswordConfig) { *cfg = config.PasswordConfig{} }, err: ErrWeakPasswordConfig},
+		{name: "low memory", modify: func(cfg *config.PasswordConfig) { cfg.Memory = 1024 }, err: ErrWeakPasswordConfig},
+		{name: "zero iterations", modify: func(cfg *config.PasswordConfig) { cfg.Iterations = 0 }, err: ErrWeakPasswordConfig},
+		{name: "zero parallelism", modify: func(cfg *config.PasswordConfig) { cfg.Parallelism = 0 }, err: ErrWeakPasswordConfig},
+		{name: "zero salt length", modify: func(cfg *config.PasswordConfig) { cfg.SaltLength = 0 }, err: ErrWeakPasswordConfig},
+		{name: "short key", modify: func(cfg *config.PasswordConfig) { cfg.KeyLength = 4 }, err: ErrWeakPasswordConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testPasswordConfig
+			tt.modify(&cfg)
+
+			if err := ValidatePasswordConfig(cfg); !goerrors.Is(err, tt.err) {
+				t.Errorf("ValidatePasswordConfig: got error %v, want %v", err, tt.err)
+			}
+			if _, err := HashPassword("password", cfg); !goerrors.Is(err, tt.err) {
+				t.Errorf("HashPassword: got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordRejectsZeroCostHash(t *testing.T) {
+	hash := "$argon2id$v=19$m=65536,t=0,p=2$AAAAAAAAAAAAAAAAAAAAAA$AAAAAAAAAAAAAAAAAAAAAA"
+
+	if _, _, err := CheckPassword("password", hash, testPasswordConfig); !goerrors.Is(err, ErrInvalidPasswordHash) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidPasswordHash)
+	}
+}
+
+func TestDummyPasswordHashMatchesNothing(t *testing.T) {
+	for _, password := range []string{"", "password"} {
+		match, needsRehash, err := CheckPassword(password, DummyPasswordHash(testPasswordConfig), testPasswordConfig)
+		if err != nil {
+			t.Fatalf("CheckPassword(%q): %v", password, err)
+		}
+		if match || needsRehash {
+			t.Errorf("CheckPassword(%q) = %v, %v, want no match and no rehash", password, match, needsRehash)
+		}
+	}
+}
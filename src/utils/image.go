@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/pkg/errors"
+	_ "golang.org/x/image/webp"
+	"pet_adopter/src/config"
+)
+
+const (
+	jpegExtension = ".jpeg"
+	pngExtension  = ".png"
+)
+
+type ProcessedImage struct {
+	Full      []byte
+	Medium    []byte
+	Thumbnail []byte
+	Extension string
+}
+
+// ProcessImage decodes an uploaded photo, applies its EXIF orientation and renders
+// resized variants. Re-encoding drops all metadata, including GPS coordinates.
+func ProcessImage(content []byte, cfg config.PhotoProcessingConfig) (ProcessedImage, error) {
+	imgConfig, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return ProcessedImage{}, errors.Wrap(err, "failed to decode image config")
+	}
+
+	if int64(imgConfig.Width)*int64(imgConfig.Height) > cfg.MaxSourcePixels {
+		return ProcessedImage{}, errors.Errorf("image too large: %dx%d", imgConfig.Width, imgConfig.Height)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(content), imaging.AutoOrientation(true))
+	if err != nil {
+		return ProcessedImage{}, errors.Wrap(err, "failed to decode image")
+	}
+
+	outputFormat, extension := imaging.JPEG, jpegExtension
+	if format == "png" {
+		outputFormat, extension = imaging.PNG, pngExtension
+	}
+
+	result := ProcessedImage{Extension: extension}
+
+	if result.Full, err = encodeImage(img, cfg.MaxDimension, outputFormat, cfg.JPEGQuality); err != nil {
+		return ProcessedImage{}, errors.Wrap(err, "failed to render full image")
+	}
+
+	if result.Medium, err = encodeImage(img, cfg.MediumDimension, outputFormat, cfg.JPEGQuality); err != nil {
+		return ProcessedImage{}, errors.Wrap(err, "failed to render medium image")
+	}
+
+	if result.Thumbnail, err = encodeImage(img, cfg.ThumbnailDimension, outputFormat, cfg.JPEGQuality); err != nil {
+		return ProcessedImage{}, errors.Wrap(err, "failed to render thumbnail image")
+	}
+
+	return result, nil
+}
+
+func encodeImage(img image.Image, maxDimension int, format imaging.Format, quality int) ([]byte, error) {
+	resized := imaging.Fit(img, maxDimension, maxDimension, imaging.Lanczos)
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, resized, format, imaging.JPEGQuality(quality)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
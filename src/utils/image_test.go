@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"pet_adopter/src/config"
+)
+
+const (
+	exifOrientationTag = 0x0112
+	exifGPSInfoTag     = 0x8825
+	gpsLatitudeRefTag  = 0x0001
+	gpsLatitudeTag     = 0x0002
+
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffASCII    = 2
+	tiffRational = 5
+)
+
+var testProcessingConfig = config.PhotoProcessingConfig{
+	MaxSourcePixels:    1_000_000,
+	MaxDimension:       2048,
+	MediumDimension:    100,
+	ThumbnailDimension: 32,
+	JPEGQuality:        85,
+}
+
+func newTestImage(width int, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment builds an APP1 segment with the orientation and a GPS latitude of 55°45' N.
+func exifSegment(orientation uint16) []byte {
+	const (
+		ifd0Offset    = 8
+		gpsIFDOffset  = ifd0Offset + 2 + 2*12 + 4
+		latitudeValue = gpsIFDOffset + 2 + 2*12 + 4
+	)
+
+	tiff := new(bytes.Buffer)
+	le := binary.LittleEndian
+	write := func(values ...interface{}) {
+		for _, value := range values {
+			_ = binary.Write(tiff, le, value)
+		}
+	}
+
+	tiff.WriteString("II")
+	write(uint16(42), uint32(ifd0Offset))
+
+	write(uint16(2))
+	write(uint16(exifOrientationTag), uint16(tiffShort), uint32(1), orientation, uint16(0))
+	write(uint16(exifGPSInfoTag), uint16(tiffLong), uint32(1), uint32(gpsIFDOffset))
+	write(uint32(0))
+
+	write(uint16(2))
+	write(uint16(gpsLatitudeRefTag), uint16(tiffASCII), uint32(2), []byte{'N', 0, 0, 0})
+	write(uint16(gpsLatitudeTag), uint16(tiffRational), uint32(3), uint32(latitudeValue))
+	write(uint32(0))
+	write(uint32(55), uint32(1), uint32(45), uint32(1), uint32(0), uint32(1))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithExif encodes the image and puts the EXIF segment right after the start of image marker.
+func jpegWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+
+	encoded := buf.Bytes()
+	result := append([]byte{}, encoded[:2]...)
+	result = append(result, exifSegment(orientation)...)
+	return append(result, encoded[2:]...)
+}
+
+func pngImage(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// jpegAppMarkers lists the APPn markers before the image data, APP0 is the JFIF header and carries no metadata.
+func jpegAppMarkers(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	markers := make([]byte, 0)
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			t.Fatalf("broken jpeg at %d", i)
+		}
+		marker := data[i+1]
+		if marker == 0xDA {
+			break
+		}
+		if marker >= 0xE1 && marker <= 0xEF {
+			markers = append(markers, marker)
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return markers
+}
+
+func TestProcessImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       func(t *testing.T) []byte
+		wantExtension string
+		wantFull      image.Point
+		wantMedium    image.Point
+		wantThumbnail image.Point
+	}{
+		{
+			name:          "jpeg rotated by exif",
+			content:       func(t *testing.T) []byte { return jpegWithExif(t, newTestImage(400, 200), 6) },
+			wantExtension: jpegExtension,
+			wantFull:      image.Pt(200, 400),
+			wantMedium:    image.Pt(50, 100),
+			wantThumbnail: image.Pt(16, 32),
+		},
+		{
+			name:          "jpeg in normal orientation",
+			content:       func(t *testing.T) []byte { return jpegWithExif(t, newTestImage(400, 200), 1) },
+			wantExtension: jpegExtension,
+			wantFull:      image.Pt(400, 200),
+			wantMedium:    image.Pt(100, 50),
+			wantThumbnail: image.Pt(32, 16),
+		},
+		{
+			name:          "png",
+			content:       func(t *testing.T) []byte { return pngImage(t, newTestImage(64, 64)) },
+			wantExtension: pngExtension,
+			wantFull:      image.Pt(64, 64),
+			wantMedium:    image.Pt(64, 64),
+			wantThumbnail: image.Pt(32, 32),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ProcessImage(tt.content(t), testProcessingConfig)
+			if err != nil {
+				t.Fatalf("ProcessImage: %v", err)
+			}
+
+			if result.Extension != tt.wantExtension {
+				t.Errorf("extension = %s, want %s", result.Extension, tt.wantExtension)
+			}
+
+			variants := []struct {
+				name string
+				data []byte
+				want image.Point
+			}{
+				{name: "full", data: result.Full, want: tt.wantFull},
+				{name: "medium", data: result.Medium, want: tt.wantMedium},
+				{name: "thumbnail", data: result.Thumbnail, want: tt.wantThumbnail},
+			}
+
+			for _, variant := range variants {
+				imgConfig, _, err := image.DecodeConfig(bytes.NewReader(variant.data))
+				if err != nil {
+					t.Fatalf("%s: DecodeConfig: %v", variant.name, err)
+				}
+				if got := image.Pt(imgConfig.Width, imgConfig.Height); got != variant.want {
+					t.Errorf("%s: size = %v, want %v", variant.name, got, variant.want)
+				}
+
+				if bytes.Contains(variant.data, []byte("Exif")) {
+					t.Errorf("%s: EXIF is left in the output", variant.name)
+				}
+				if tt.wantExtension == jpegExtension {
+					if markers := jpegAppMarkers(t, variant.data); len(markers) > 0 {
+						t.Errorf("%s: metadata segments %x are left in the output", variant.name, markers)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProcessImageRejectsTooLargeSource(t *testing.T) {
+	cfg := testProcessingConfig
+	cfg.MaxSourcePixels = 100
+
+	if _, err := ProcessImage(pngImage(t, newTestImage(20, 20)), cfg); err == nil {
+		t.Error("ProcessImage accepted an image over MaxSourcePixels")
+	}
+}
+
+func TestJPEGWithExifCarriesMetadata(t *testing.T) {
+	content := jpegWithExif(t, newTestImage(40, 20), 6)
+
+	if markers := jpegAppMarkers(t, content); len(markers) != 1 || markers[0] != 0xE1 {
+		t.Fatalf("input markers = %x, want the APP1 EXIF segment", markers)
+	}
+	if !bytes.Contains(content, []byte("Exif")) {
+		t.Fatal("input has no EXIF header")
+	}
+}
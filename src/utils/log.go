@@ -11,9 +11,10 @@ import (
 )
 
 const (
-	Internal = "internal"
-	Invalid  = "invalid"
-	NotFound = "not_found"
+	Internal  = "internal"
+	Invalid   = "invalid"
+	NotFound  = "not_found"
+	Forbidden = "forbidden"
 
 	MsgErrMarshalResponse  = "failed to unmarshal request"
 	MsgErrUnmarshalRequest = "failed to unmarshal request"
@@ -51,9 +52,28 @@ func LogError(ctx context.Context, err error, msg string) {
 	logger.Error(errors.Wrap(err, msg).Error())
 }
 
-func LogNotAnAdminError(ctx context.Context) {
-	logger := GetLoggerFromContext(ctx)
-	logger.Error("not an admin")
+func GetUserRoleFromContext(ctx context.Context) string {
+	if role, ok := ctx.Value(config.UserRoleContextKey).(string); ok {
+		return role
+	}
+
+	return ""
+}
+
+func GetSessionIDFromContext(ctx context.Context) uuid.UUID {
+	if sessionID, ok := ctx.Value(config.SessionIDContextKey).(uuid.UUID); ok {
+		return sessionID
+	}
+
+	return uuid.Nil
+}
+
+func GetRequestIDFromContext(ctx context.Context) string {
+	if reqID, ok := ctx.Value(config.RequestIDContextKey).(string); ok {
+		return reqID
+	}
+
+	return ""
 }
 
 func LogErrorMessage(ctx context.Context, msg string) {
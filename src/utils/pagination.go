@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"pet_adopter/src/config"
+)
+
+func GetPaginationFromQuery(query url.Values, cfg config.AdConfig) (int, int, error) {
+	limit := cfg.DefaultSearchLimit
+	offset := cfg.DefaultSearchOffset
+
+	limitString := query.Get("limit")
+	if limitString != "" {
+		limit64, err := strconv.ParseInt(limitString, 10, 64)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse limit")
+		}
+		limit = min(int(limit64), cfg.MaxSearchLimit)
+	}
+
+	offsetString := query.Get("offset")
+	if offsetString != "" {
+		offset64, err := strconv.ParseInt(offsetString, 10, 64)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse offset")
+		}
+		offset = int(offset64)
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"pet_adopter/src/config"
+)
+
+const (
+	TokenEncodingBase64URL = "base64url"
+	TokenEncodingHex       = "hex"
+
+	// MinTokenEntropy keeps a misconfigured token guessable by nobody, 16 bytes are 128 bits.
+	MinTokenEntropy = 16
+)
+
+var (
+	ErrWeakToken            = errors.New("token entropy is too low")
+	ErrUnknownTokenEncoding = errors.New("unknown token encoding")
+)
+
+// ValidateTokenConfig reports a config GenerateToken would fail on, so it can be rejected at startup
+// instead of on the first login.
+func ValidateTokenConfig(cfg config.TokenConfig) error {
+	_, err := tokenEncoder(cfg)
+	return err
+}
+
+// GenerateToken returns cfg.Entropy bytes from crypto/rand in the configured encoding, both are safe
+// in URLs, cookies and Redis keys. It is meant for every secret token: sessions, resets, verifications.
+func GenerateToken(cfg config.TokenConfig) (string, error) {
+	encode, err := tokenEncoder(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	token := make([]byte, cfg.Entropy)
+	if _, err = rand.Read(token); err != nil {
+		return "", errors.Wrap(err, "failed to read random bytes")
+	}
+
+	return encode(token), nil
+}
+
+func tokenEncoder(cfg config.TokenConfig) (func([]byte) string, error) {
+	if cfg.Entropy < MinTokenEntropy {
+		return nil, ErrWeakToken
+	}
+
+	switch cfg.Encoding {
+	case TokenEncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString, nil
+	case TokenEncodingHex:
+		return hex.EncodeToString, nil
+	default:
+		return nil, errors.Wrapf(ErrUnknownTokenEncoding, "encoding %q", cfg.Encoding)
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	goerrors "errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"pet_adopter/src/config"
+)
+
+func TestGenerateTokenIsUniqueUnderParallelGeneration(t *testing.T) {
+	const (
+		workers   = 64
+		perWorker = 2000
+	)
+	cfg := config.TokenConfig{Entropy: MinTokenEntropy, Encoding: TokenEncodingBase64URL}
+
+	tokens := make(chan string, workers*perWorker)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < perWorker; j++ {
+				token, err := GenerateToken(cfg)
+				if err != nil {
+					errs <- err
+					return
+				}
+				tokens <- token
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(tokens)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+
+	seen := make(map[string]struct{}, workers*perWorker)
+	for token := range tokens {
+		if _, ok := seen[token]; ok {
+			t.Fatalf("token %q generated twice", token)
+		}
+		seen[token] = struct{}{}
+	}
+
+	if len(seen) != workers*perWorker {
+		t.Fatalf("got %d tokens, want %d", len(seen), workers*perWorker)
+	}
+}
+
+func TestGenerateTokenEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.TokenConfig
+		length  int
+		pattern *regexp.Regexp
+	}{
+		{
+			name:    "base64url",
+			cfg:     config.TokenConfig{Entropy: 32, Encoding: TokenEncodingBase64URL},
+			length:  43,
+			pattern: regexp.MustCompile(`^[A-Za-z0-9_-]+$`),
+		},
+		{
+			name:    "hex",
+			cfg:     config.TokenConfig{Entropy: 16, Encoding: TokenEncodingHex},
+			length:  32,
+			pattern: regexp.MustCompile(`^[0-9a-f]+$`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateToken(tt.cfg)
+			if err != nil {
+				t.Fatalf("GenerateToken failed: %v", err)
+			}
+
+			if len(token) != tt.length {
+				t.Errorf("token length is %d, want %d", len(token), tt.length)
+			}
+
+			if !tt.pattern.MatchString(token) {
+				t.Errorf("token %q does not match %s", token, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TokenConfig
+		err  error
+	}{
+		{
+			name: "weak entropy",
+			cfg:  config.TokenConfig{Entropy: MinTokenEntropy - 1, Encoding: TokenEncodingHex},
+			err:  ErrWeakToken,
+		},
+		{
+			name: "unknown encoding",
+			cfg:  config.TokenConfig{Entropy: 32, Encoding: "base32"},
+			err:  ErrUnknownTokenEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTokenConfig(tt.cfg); !goerrors.Is(err, tt.err) {
+				t.Errorf("ValidateTokenConfig: got error %v, want %v", err, tt.err)
+			}
+			if _, err := GenerateToken(tt.cfg); !goerrors.Is(err, tt.err) {
+				t.Errorf("GenerateToken: got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
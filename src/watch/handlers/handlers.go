@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pet_adopter/src/ad"
+	"pet_adopter/src/config"
+	"pet_adopter/src/utils"
+	"pet_adopter/src/watch"
+)
+
+type WatchHandler struct {
+	logic watch.WatchLogic
+	cfg   config.AdConfig
+}
+
+func NewWatchHandler(logic watch.WatchLogic, cfg config.AdConfig) *WatchHandler {
+	return &WatchHandler{
+		logic: logic,
+		cfg:   cfg,
+	}
+}
+
+type GetRecentlyViewedResponse struct {
+	Ads []ad.RespAd `json:"ads"`
+}
+
+func (h *WatchHandler) GetRecentlyViewed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := utils.GetPaginationFromQuery(r.URL.Query(), h.cfg)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to parse pagination params")
+		http.Error(w, utils.Invalid, http.StatusBadRequest)
+		return
+	}
+
+	viewed, err := h.logic.GetRecentlyViewed(ctx, limit, offset)
+	if err != nil {
+		utils.LogError(ctx, err, "failed to get recently viewed ads")
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+
+	result := GetRecentlyViewedResponse{Ads: viewed}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		utils.LogError(ctx, err, utils.MsgErrMarshalResponse)
+		http.Error(w, utils.Internal, http.StatusInternalServerError)
+		return
+	}
+}
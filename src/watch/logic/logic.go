@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/utils"
+	"pet_adopter/src/watch"
+)
+
+type WatchLogic struct {
+	repo   watch.WatchRepo
+	adRepo ad.AdRepo
+}
+
+func NewWatchLogic(repo watch.WatchRepo, adRepo ad.AdRepo) WatchLogic {
+	return WatchLogic{
+		repo:   repo,
+		adRepo: adRepo,
+	}
+}
+
+func (l *WatchLogic) RecordView(ctx context.Context, userID uuid.UUID, adID uuid.UUID) error {
+	if userID == uuid.Nil {
+		if err := l.repo.IncAnonymousViews(ctx, adID); err != nil {
+			return errors.Wrap(err, "failed to increment anonymous views")
+		}
+		return nil
+	}
+
+	row := watch.Watch{
+		UserID:    userID,
+		AdID:      adID,
+		CreatedAt: time.Now().Local(),
+	}
+
+	if err := l.repo.SaveWatch(ctx, row); err != nil {
+		return errors.Wrap(err, "failed to save watch")
+	}
+
+	return nil
+}
+
+func (l *WatchLogic) GetRecentlyViewed(ctx context.Context, limit int, offset int) ([]ad.RespAd, error) {
+	adIDs, err := l.repo.GetWatchedAdIDs(ctx, utils.GetUserIDFromContext(ctx), limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get watched ad ids")
+	}
+
+	return l.adRepo.GetAdsByIDs(ctx, adIDs)
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/pkg/errors"
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+	"pet_adopter/src/watch"
+)
+
+const (
+	saveWatch = `
+INSERT INTO
+	Watch(user_id, ad_id, created_at)
+VALUES
+	($1, $2, $3)
+ON
+	CONFLICT (user_id, ad_id) DO
+UPDATE
+	SET created_at = $3;
+`
+	incAnonymousViews = `UPDATE Ad SET anonymous_views = anonymous_views + 1 WHERE id = $1;`
+	getWatchedAdIDs   = `SELECT ad_id FROM Watch WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;`
+)
+
+type WatchPostgres struct {
+	db pgxtype.Querier
+}
+
+func NewWatchPostgres(db pgxtype.Querier) *WatchPostgres {
+	return &WatchPostgres{db: db}
+}
+
+func (repo *WatchPostgres) SaveWatch(ctx context.Context, row watch.Watch) error {
+	if _, err := repo.db.Exec(ctx, saveWatch, row.UserID, row.AdID, row.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ad.ErrAdNotFound
+		}
+		return errors.Wrap(err, "failed to save watch to postgres")
+	}
+
+	return nil
+}
+
+func (repo *WatchPostgres) IncAnonymousViews(ctx context.Context, adID uuid.UUID) error {
+	if _, err := repo.db.Exec(ctx, incAnonymousViews, adID); err != nil {
+		return errors.Wrap(err, "failed to increment anonymous views in postgres")
+	}
+
+	return nil
+}
+
+func (repo *WatchPostgres) GetWatchedAdIDs(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0)
+
+	rows, err := repo.db.Query(ctx, getWatchedAdIDs, userID, limit, offset)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get watches from postgres")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adID uuid.UUID
+		if err = rows.Scan(&adID); err != nil {
+			return result, errors.Wrap(err, "failed to parse watch")
+		}
+		result = append(result, adID)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,26 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/satori/uuid"
+	"pet_adopter/src/ad"
+)
+
+type Watch struct {
+	UserID    uuid.UUID `json:"user_id"`
+	AdID      uuid.UUID `json:"ad_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WatchRepo interface {
+	SaveWatch(ctx context.Context, watch Watch) error
+	IncAnonymousViews(ctx context.Context, adID uuid.UUID) error
+	GetWatchedAdIDs(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]uuid.UUID, error)
+}
+
+type WatchLogic interface {
+	RecordView(ctx context.Context, userID uuid.UUID, adID uuid.UUID) error
+	GetRecentlyViewed(ctx context.Context, limit int, offset int) ([]ad.RespAd, error)
+}